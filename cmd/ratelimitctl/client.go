@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/admin"
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"github.com/alexduzi/labratelimiter/internal/storage/backends"
+)
+
+// client abstrai se as operações vão direto ao storage ou passam pela API de administração
+type client interface {
+	ListBlocked(ctx context.Context) (dto.AdminBlockedList, error)
+	State(ctx context.Context, kind, id string) (dto.AdminKeyState, error)
+	Block(ctx context.Context, kind, id string, duration time.Duration, reason string) (dto.AdminKeyState, error)
+	Unblock(ctx context.Context, kind, id string) (dto.AdminKeyState, error)
+	Reset(ctx context.Context, kind, id string) (dto.AdminKeyState, error)
+	Close() error
+}
+
+// directClient abre o storage configurado via env/.env, como o servidor faria,
+// e registra os bloqueios manuais no mesmo audit log
+type directClient struct {
+	store storage.Storage
+	rl    *limiter.RateLimiter
+	audit *audit.Logger
+}
+
+func newDirectClient(cfg *config.Config) (*directClient, error) {
+	policies, err := limiterconfig.PolicyOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := audit.NewHasher([]byte(cfg.AuditHashKey))
+	if cfg.AuditHashKey == "" {
+		hasher = audit.NewRandomHasher()
+	}
+	// a saída padrão é do resultado do comando
+	dest := cfg.AuditLog
+	if dest == "stdout" {
+		dest = "stderr"
+	}
+	auditLog, err := audit.New(dest, hasher)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := backends.New(cfg)
+	if err != nil {
+		auditLog.Close()
+		return nil, err
+	}
+
+	return &directClient{store: store, rl: limiterconfig.New(store, cfg, policies...), audit: auditLog}, nil
+}
+
+func (c *directClient) ListBlocked(ctx context.Context) (dto.AdminBlockedList, error) {
+	blocked, err := c.rl.ListBlocked(ctx)
+	if err != nil {
+		return dto.AdminBlockedList{}, err
+	}
+
+	return admin.NewBlockedList(blocked, time.Now()), nil
+}
+
+func (c *directClient) State(ctx context.Context, kind, id string) (dto.AdminKeyState, error) {
+	state, err := c.rl.State(ctx, kind, id)
+	if err != nil {
+		return dto.AdminKeyState{}, err
+	}
+
+	return admin.NewKeyState(kind, id, state), nil
+}
+
+func (c *directClient) Block(ctx context.Context, kind, id string, duration time.Duration, reason string) (dto.AdminKeyState, error) {
+	if err := c.rl.Block(ctx, kind, id, duration); err != nil {
+		return dto.AdminKeyState{}, err
+	}
+
+	c.audit.ManualBlock(ctx, kind, id, duration, reason)
+	return c.State(ctx, kind, id)
+}
+
+func (c *directClient) Unblock(ctx context.Context, kind, id string) (dto.AdminKeyState, error) {
+	if err := c.rl.Unblock(ctx, kind, id); err != nil {
+		return dto.AdminKeyState{}, err
+	}
+
+	return c.State(ctx, kind, id)
+}
+
+func (c *directClient) Reset(ctx context.Context, kind, id string) (dto.AdminKeyState, error) {
+	if err := c.rl.Reset(ctx, kind, id); err != nil {
+		return dto.AdminKeyState{}, err
+	}
+
+	return c.State(ctx, kind, id)
+}
+
+func (c *directClient) Close() error {
+	return errors.Join(c.store.Close(), c.audit.Close())
+}
+
+// apiClient usa a API de administração de um servidor em execução
+type apiClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newAPIClient(baseURL, token string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func keyPath(kind, id string) string {
+	return "/v1/keys/" + url.PathEscape(kind) + "/" + url.PathEscape(id)
+}
+
+func (c *apiClient) ListBlocked(ctx context.Context) (dto.AdminBlockedList, error) {
+	var response dto.AdminBlockedList
+	err := c.do(ctx, http.MethodGet, "/v1/blocked", nil, &response)
+	return response, err
+}
+
+func (c *apiClient) State(ctx context.Context, kind, id string) (dto.AdminKeyState, error) {
+	var response dto.AdminKeyState
+	err := c.do(ctx, http.MethodGet, keyPath(kind, id), nil, &response)
+	return response, err
+}
+
+func (c *apiClient) Block(ctx context.Context, kind, id string, duration time.Duration, reason string) (dto.AdminKeyState, error) {
+	var response dto.AdminKeyState
+	body := dto.AdminBlockRequest{Duration: duration.String(), Reason: reason}
+	err := c.do(ctx, http.MethodPost, keyPath(kind, id)+"/block", body, &response)
+	return response, err
+}
+
+func (c *apiClient) Unblock(ctx context.Context, kind, id string) (dto.AdminKeyState, error) {
+	var response dto.AdminKeyState
+	err := c.do(ctx, http.MethodDelete, keyPath(kind, id)+"/block", nil, &response)
+	return response, err
+}
+
+func (c *apiClient) Reset(ctx context.Context, kind, id string) (dto.AdminKeyState, error) {
+	var response dto.AdminKeyState
+	err := c.do(ctx, http.MethodPost, keyPath(kind, id)+"/reset", nil, &response)
+	return response, err
+}
+
+func (c *apiClient) Close() error {
+	return nil
+}
+
+func (c *apiClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("admin API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr dto.ResponseMessage
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("admin API returned %d: %s", resp.StatusCode, apiErr.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
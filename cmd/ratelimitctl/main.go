@@ -0,0 +1,275 @@
+// ratelimitctl é a ferramenta de linha de comando para operar o rate limiter
+// durante incidentes: listar, inspecionar, bloquear e desbloquear IPs/tokens.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/startup"
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: ratelimitctl [global flags] <command> [flags] [args]
+
+Commands:
+  blocked                          list blocked IPs/tokens
+  inspect <kind> <id>              show counter, window and block of an IP/token
+  block [-duration d] [-reason r] <kind> <id>
+                                   block an IP/token manually; the reason goes to the audit log
+  unblock <kind> <id>              remove the block, keeping the counter
+  reset <kind> <id>                remove counter and block
+  export [-format json|csv]        export the blocked IPs/tokens
+  validate                         validate the configuration without connecting to storage
+
+<kind> is "ip" or "token".
+
+Global flags:
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "ratelimitctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	global := flag.NewFlagSet("ratelimitctl", flag.ContinueOnError)
+	envFile := global.String("env-file", ".env", "env file with the server configuration")
+	apiURL := global.String("api", "", "admin API base URL (e.g. http://localhost:9090); empty talks to the storage directly")
+	token := global.String("token", os.Getenv("ADMIN_TOKEN"), "admin API token (defaults to $ADMIN_TOKEN)")
+	output := global.String("o", "table", "output format: table or json")
+	global.Usage = func() {
+		fmt.Fprint(global.Output(), usage)
+		global.PrintDefaults()
+	}
+
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return errors.New("missing command")
+	}
+
+	// as variáveis do arquivo sobrescrevem o ambiente para que "validate" teste exatamente o arquivo
+	if err := godotenv.Overload(*envFile); err != nil && !(errors.Is(err, os.ErrNotExist) && *envFile == ".env") {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	command, commandArgs := global.Arg(0), global.Args()[1:]
+
+	if command == "validate" {
+		return validate(cfg, out)
+	}
+
+	var c client
+	if *apiURL != "" {
+		c = newAPIClient(*apiURL, *token)
+	} else {
+		direct, err := newDirectClient(cfg)
+		if err != nil {
+			return err
+		}
+		c = direct
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch command {
+	case "blocked":
+		list, err := c.ListBlocked(ctx)
+		if err != nil {
+			return err
+		}
+		return printBlocked(out, *output, list)
+
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ContinueOnError)
+		format := fs.String("format", "json", "export format: json or csv")
+		if err := fs.Parse(commandArgs); err != nil {
+			return err
+		}
+
+		list, err := c.ListBlocked(ctx)
+		if err != nil {
+			return err
+		}
+		return export(out, *format, list)
+
+	case "inspect", "unblock", "reset":
+		kind, id, err := keyArgs(command, commandArgs)
+		if err != nil {
+			return err
+		}
+
+		var state dto.AdminKeyState
+		switch command {
+		case "inspect":
+			state, err = c.State(ctx, kind, id)
+		case "unblock":
+			state, err = c.Unblock(ctx, kind, id)
+		case "reset":
+			state, err = c.Reset(ctx, kind, id)
+		}
+		if err != nil {
+			return err
+		}
+		return printState(out, *output, state)
+
+	case "block":
+		fs := flag.NewFlagSet("block", flag.ContinueOnError)
+		duration := fs.Duration("duration", 0, "block duration (default: the block duration of the kind's policy)")
+		reason := fs.String("reason", "", "reason recorded in the audit log")
+		if err := fs.Parse(commandArgs); err != nil {
+			return err
+		}
+
+		kind, id, err := keyArgs(command, fs.Args())
+		if err != nil {
+			return err
+		}
+		if !flagSet(fs, "duration") {
+			if *duration, err = defaultBlockDuration(cfg, kind); err != nil {
+				return err
+			}
+		}
+		if *duration <= 0 {
+			return errors.New("duration must be positive")
+		}
+
+		state, err := c.Block(ctx, kind, id, *duration, *reason)
+		if err != nil {
+			return err
+		}
+		return printState(out, *output, state)
+
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func keyArgs(command string, args []string) (kind, id string, err error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("usage: ratelimitctl %s <kind> <id>", command)
+	}
+	return args[0], args[1], nil
+}
+
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// defaultBlockDuration é o bloqueio da política do kind, o mesmo aplicado
+// quando a chave excede o limite
+func defaultBlockDuration(cfg *config.Config, kind string) (time.Duration, error) {
+	policies, err := limiter.ParsePolicies(cfg.Policies)
+	if err != nil {
+		return 0, fmt.Errorf("invalid config: POLICIES: %w", err)
+	}
+	for _, p := range policies {
+		if p.Name == kind {
+			return p.BlockDuration, nil
+		}
+	}
+
+	switch kind {
+	case limiter.KindIP:
+		return cfg.IpBlockDuration, nil
+	case limiter.KindToken:
+		return cfg.TokenBlockDuration, nil
+	default:
+		return 0, fmt.Errorf("no block duration configured for %q, use -duration", kind)
+	}
+}
+
+// validate checa a configuração como o servidor faz na inicialização, sem abrir
+// conexões com o storage
+func validate(cfg *config.Config, out io.Writer) error {
+	if _, err := startup.Parse(cfg); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	fmt.Fprintf(out, "config OK (storage backend: %s)\n", cfg.StorageBackend)
+	return nil
+}
+
+func printBlocked(out io.Writer, format string, list dto.AdminBlockedList) error {
+	if format == "json" {
+		return writeJSON(out, list)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tID\tBLOCKED UNTIL\tEXPIRES IN")
+	for _, k := range list.Keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", k.Kind, k.ID, k.BlockedUntil.Format(time.RFC3339), k.ExpiresIn)
+	}
+	return tw.Flush()
+}
+
+func printState(out io.Writer, format string, state dto.AdminKeyState) error {
+	if format == "json" {
+		return writeJSON(out, state)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Kind:\t%s\n", state.Kind)
+	fmt.Fprintf(tw, "ID:\t%s\n", state.ID)
+	fmt.Fprintf(tw, "Count:\t%d\n", state.Count)
+	if state.WindowStart != nil {
+		fmt.Fprintf(tw, "Window:\t%s - %s\n", state.WindowStart.Format(time.RFC3339Nano), state.WindowEnd.Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(tw, "Blocked:\t%t\n", state.Blocked)
+	if state.BlockedUntil != nil {
+		fmt.Fprintf(tw, "Blocked until:\t%s\n", state.BlockedUntil.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+func export(out io.Writer, format string, list dto.AdminBlockedList) error {
+	switch format {
+	case "json":
+		return writeJSON(out, list)
+	case "csv":
+		w := csv.NewWriter(out)
+		w.Write([]string{"kind", "id", "blocked_until", "expires_in_seconds"})
+		now := time.Now()
+		for _, k := range list.Keys {
+			w.Write([]string{k.Kind, k.ID, k.BlockedUntil.Format(time.RFC3339), strconv.Itoa(int(k.BlockedUntil.Sub(now).Seconds()))})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func writeJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/admin"
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+// writeEnvFile grava o .env usado pelo comando; t.Setenv garante que as
+// variáveis carregadas por godotenv sejam restauradas no fim do teste
+func writeEnvFile(t *testing.T, env map[string]string) string {
+	t.Helper()
+
+	var b strings.Builder
+	for k, v := range env {
+		t.Setenv(k, "")
+		b.WriteString(k + "=" + v + "\n")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	return path
+}
+
+func TestRun_RejectsInvalidArguments(t *testing.T) {
+	envFile := writeEnvFile(t, map[string]string{"STORAGE_BACKEND": "memory"})
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no command", nil, "missing command"},
+		{"unknown command", []string{"purge"}, `unknown command "purge"`},
+		{"unknown global flag", []string{"-verbose", "blocked"}, "flag provided but not defined"},
+		{"missing key", []string{"inspect", "ip"}, "usage: ratelimitctl inspect <kind> <id>"},
+		{"extra args", []string{"unblock", "ip", "1.2.3.4", "5.6.7.8"}, "usage: ratelimitctl unblock <kind> <id>"},
+		{"invalid duration", []string{"block", "-duration", "soon", "ip", "1.2.3.4"}, "invalid value"},
+		{"non positive duration", []string{"block", "-duration", "0s", "ip", "1.2.3.4"}, "duration must be positive"},
+		{"unknown kind without duration", []string{"block", "user", "42"}, `no block duration configured for "user"`},
+		{"unknown export format", []string{"export", "-format", "xml"}, `unknown export format "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-env-file", envFile}, tt.args...)
+
+			var out bytes.Buffer
+			err := run(args, &out)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRun_BlockDefaultsToTheKindBlockDuration(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	envFile := writeEnvFile(t, map[string]string{
+		"STORAGE_BACKEND":      "memory",
+		"IP_BLOCK_DURATION":    "1m",
+		"TOKEN_BLOCK_DURATION": "2h",
+		"POLICIES":             "login=5/1m/15m",
+		"AUDIT_LOG":            auditPath,
+	})
+
+	tests := []struct {
+		name string
+		args []string
+		want time.Duration
+	}{
+		{"ip", []string{"ip", "1.2.3.4"}, time.Minute},
+		{"token", []string{"token", "abc"}, 2 * time.Hour},
+		{"named policy", []string{"login", "1.2.3.4"}, 15 * time.Minute},
+		{"explicit duration", []string{"-duration", "10s", "token", "abc"}, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-env-file", envFile, "-o", "json", "block", "-reason", "incident-42"}, tt.args...)
+
+			var out bytes.Buffer
+			before := time.Now()
+			if err := run(args, &out); err != nil {
+				t.Fatalf("block: %v", err)
+			}
+
+			var state dto.AdminKeyState
+			if err := json.Unmarshal(out.Bytes(), &state); err != nil {
+				t.Fatalf("invalid output %q: %v", out.String(), err)
+			}
+			if !state.Blocked || state.BlockedUntil == nil {
+				t.Fatalf("expected key to be blocked, got %+v", state)
+			}
+			if got := state.BlockedUntil.Sub(before); got < tt.want-time.Second || got > tt.want+time.Second {
+				t.Errorf("expected block of %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	// o motivo vai para o audit log, não só para o log do processo
+	entries, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if got := strings.Count(string(entries), `"reason":"incident-42"`); got != len(tests) {
+		t.Errorf("expected %d audit entries with the reason, got %d:\n%s", len(tests), got, entries)
+	}
+}
+
+func TestRun_UsesAdminAPI(t *testing.T) {
+	envFile := writeEnvFile(t, map[string]string{"STORAGE_BACKEND": "memory"})
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{IpLimitRps: 5, IpBlockDuration: time.Minute, TokenLimitRps: 5, TokenBlockDuration: time.Minute}
+	rl := limiterconfig.New(store, cfg)
+
+	var auditBuf bytes.Buffer
+	auditLog := audit.NewWithWriter(&auditBuf, nil, audit.NewHasher([]byte("test-secret")))
+
+	server := httptest.NewServer(admin.NewHandler(rl, "secret", admin.WithAuditLog(auditLog)))
+	t.Cleanup(server.Close)
+
+	api := []string{"-env-file", envFile, "-api", server.URL + "/", "-token", "secret", "-o", "json"}
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantBlocked bool
+		wantOutput  string
+	}{
+		{"block", []string{"block", "-duration", "1h", "-reason", "scraping", "ip", "1.2.3.4"}, true, `"blocked": true`},
+		{"inspect", []string{"inspect", "ip", "1.2.3.4"}, true, `"id": "1.2.3.4"`},
+		{"blocked", []string{"blocked"}, true, `"kind": "ip"`},
+		{"unblock", []string{"unblock", "ip", "1.2.3.4"}, false, `"blocked": false`},
+		{"reset", []string{"reset", "ip", "1.2.3.4"}, false, `"count": 0`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(append(api, tt.args...), &out); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.wantOutput, out.String())
+			}
+
+			state, err := rl.State(t.Context(), limiter.KindIP, "1.2.3.4")
+			if err != nil {
+				t.Fatalf("state: %v", err)
+			}
+			if blocked := !state.BlockedUntil.IsZero(); blocked != tt.wantBlocked {
+				t.Errorf("expected blocked=%v on the server, got %v", tt.wantBlocked, blocked)
+			}
+		})
+	}
+
+	if !strings.Contains(auditBuf.String(), `"reason":"scraping"`) {
+		t.Errorf("expected the reason in the server audit log, got:\n%s", auditBuf.String())
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		args := []string{"-env-file", envFile, "-api", server.URL, "-token", "wrong", "blocked"}
+		if err := run(args, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "admin API returned 401") {
+			t.Errorf("expected unauthorized error, got %v", err)
+		}
+	})
+}
+
+func TestRun_ValidateChecksServerSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"invalid policy", map[string]string{"POLICIES": "login=abc/1m"}, `POLICIES: policy login: invalid limit "abc"`},
+		{"invalid trusted proxy", map[string]string{"TRUSTED_PROXIES": "10.0.0.0/99"}, "TRUSTED_PROXIES"},
+		{"invalid upstream route", map[string]string{"UPSTREAM_ROUTES": "api=http://api:8080"}, "UPSTREAM_ROUTES"},
+		{"unknown descriptor policy", map[string]string{"RLS_PORT": "8081", "RLS_DESCRIPTORS": "user_id=user"}, "RLS_DESCRIPTORS"},
+		{"unknown ext_authz policy", map[string]string{"EXTAUTHZ_PORT": "9191", "EXTAUTHZ_POLICIES": "login"}, `EXTAUTHZ_POLICIES: unknown policy "login"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env["STORAGE_BACKEND"] = "memory"
+			envFile := writeEnvFile(t, tt.env)
+
+			var out bytes.Buffer
+			err := run([]string{"-env-file", envFile, "validate"}, &out)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v (output %q)", tt.wantErr, err, out.String())
+			}
+		})
+	}
+
+	envFile := writeEnvFile(t, map[string]string{
+		"STORAGE_BACKEND":   "memory",
+		"POLICIES":          "login=5/1m",
+		"EXTAUTHZ_PORT":     "9191",
+		"EXTAUTHZ_POLICIES": "login",
+	})
+	var out bytes.Buffer
+	if err := run([]string{"-env-file", envFile, "validate"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "config OK") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
@@ -1,64 +1,252 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/alexduzi/labratelimiter/internal/admin"
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/check"
 	"github.com/alexduzi/labratelimiter/internal/config"
 	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/envoy"
+	"github.com/alexduzi/labratelimiter/internal/events"
+	"github.com/alexduzi/labratelimiter/internal/health"
 	"github.com/alexduzi/labratelimiter/internal/limiter"
-	"github.com/alexduzi/labratelimiter/internal/middleware"
-	"github.com/alexduzi/labratelimiter/internal/storage"
+	"github.com/alexduzi/labratelimiter/internal/logging"
+	"github.com/alexduzi/labratelimiter/internal/metrics"
+	"github.com/alexduzi/labratelimiter/internal/offenders"
+	"github.com/alexduzi/labratelimiter/internal/proxy"
+	"github.com/alexduzi/labratelimiter/internal/server"
+	"github.com/alexduzi/labratelimiter/internal/startup"
+	"github.com/alexduzi/labratelimiter/internal/storage/backends"
+	"github.com/alexduzi/labratelimiter/internal/tracing"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	if err := run(); err != nil {
+		slog.Error("Server stopped with error", slog.Any("error", err))
+		os.Exit(1)
 	}
+}
+
+// run inicializa as dependências e serve até receber SIGINT/SIGTERM. Os defers
+// fecham tudo na ordem inversa: primeiro os servidores param de aceitar
+// requisições, depois eventos e top offenders são enviados e só então o storage
+// é fechado.
+func run() error {
+	envErr := godotenv.Load()
 
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	store, err := storage.NewRedisStorage(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	// a mesma validação do "ratelimitctl validate"
+	settings, err := startup.Parse(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		return fmt.Errorf("invalid config: %w", err)
 	}
-	defer store.Close()
+	trusted := settings.TrustedProxies
 
-	rl := limiter.NewRateLimiter(store, cfg)
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return fmt.Errorf("failed to setup logging: %w", err)
+	}
+	slog.SetDefault(logger)
 
-	mux := http.NewServeMux()
+	if envErr != nil {
+		slog.Info("No .env file found, using environment variables")
+	}
 
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		response := dto.ResponseMessage{
-			Message: "OK",
-		}
-		json.NewEncoder(w).Encode(response)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// SHUTDOWN_TIMEOUT vale para o encerramento inteiro, não para cada etapa
+	shutdown := server.NewDeadline(cfg.ShutdownTimeout)
+	defer shutdown.Cancel()
+
+	// O mesmo hasher é usado no audit log, nos eventos e na API admin, para que
+	// os key_hash possam ser correlacionados
+	hasher := audit.NewHasher([]byte(cfg.AuditHashKey))
+	if cfg.AuditHashKey == "" {
+		slog.Warn("AUDIT_HASH_KEY not set, using a random key; key hashes change on restart and differ between instances")
+		hasher = audit.NewRandomHasher()
+	}
+
+	auditLog, err := audit.New(cfg.AuditLog, hasher)
+	if err != nil {
+		return fmt.Errorf("failed to setup audit log: %w", err)
+	}
+	defer closeStage(shutdown, "audit log", func(context.Context) error {
+		return auditLog.Close()
 	})
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		response := dto.ResponseHealth{
-			Status: "healthy",
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing: %w", err)
+	}
+	defer closeStage(shutdown, "tracing", shutdownTracing)
+
+	store, err := backends.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to setup storage: %w", err)
+	}
+	defer closeStage(shutdown, "storage", func(context.Context) error {
+		return store.Close()
+	})
+
+	bus := events.NewBus(events.NewSinks(cfg), events.WithThreshold(cfg.EventThreshold), events.WithKeyHasher(hasher))
+	defer closeStage(shutdown, "events", bus.Close)
+
+	tracker := offenders.New(cfg, limiter.NewKeyBuilder(cfg.KeyPrefix, cfg.KeyNamespace).Prefix())
+	if tracker != nil {
+		defer closeStage(shutdown, "top offenders", tracker.Close)
+	}
+
+	m := metrics.New()
+
+	instrumented := m.InstrumentStorage(tracing.InstrumentStorage(store, cfg.StorageBackend))
+
+	opts := []ratelimit.Option{
+		ratelimit.WithIPLimit(cfg.IpLimitRps, cfg.IpBlockDuration),
+		ratelimit.WithTokenLimit(cfg.TokenLimitRps, cfg.TokenBlockDuration),
+		ratelimit.WithKeyPrefix(cfg.KeyPrefix, cfg.KeyNamespace),
+		ratelimit.WithObserver(m),
+		ratelimit.WithObserver(auditLog),
+		ratelimit.WithObserver(bus),
+		ratelimit.WithFailOpen(cfg.FailOpen),
+	}
+	for _, p := range settings.Policies {
+		opts = append(opts, ratelimit.WithPolicy(p))
+	}
+	adminOpts := []admin.Option{admin.WithAuditLog(auditLog), admin.WithKeyHasher(hasher)}
+	if tracker != nil {
+		opts = append(opts, ratelimit.WithObserver(tracker))
+		adminOpts = append(adminOpts, admin.WithOffenders(tracker))
+	}
+
+	rl, err := ratelimit.New(instrumented, opts...)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	m.RegisterBlockedKeys(rl, cfg.MetricsBlockedKeysTTL)
+	if tracker != nil {
+		m.RegisterTopOffenders(tracker, offenders.DefaultTop)
+	}
+
+	// Com UPSTREAM_URL/UPSTREAM_ROUTES as requisições permitidas vão para o
+	// upstream; sem eles o servidor só responde OK
+	var app http.Handler
+	proxied := len(settings.Upstreams) > 0
+	if proxied {
+		upstream, err := proxy.New(settings.Upstreams, cfg.UpstreamTimeout, trusted)
+		if err != nil {
+			return fmt.Errorf("failed to setup proxy: %w", err)
 		}
-		json.NewEncoder(w).Encode(response)
+		app = upstream
+	} else {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			response := dto.ResponseMessage{
+				Message: "OK",
+			}
+			json.NewEncoder(w).Encode(response)
+		})
+		app = mux
+	}
+
+	// Com fail-open a instância continua atendendo sem o storage, então a falha
+	// dele só deixa a readiness "degraded"
+	checker := health.New(cfg.ReadinessTimeout, health.Check{
+		Name:       "storage",
+		Check:      store.Ping,
+		MaxLatency: cfg.ReadinessMaxLatency,
+		Critical:   !cfg.FailOpen,
 	})
 
-	// Aplica middleware
-	handler := middleware.RateLimiter(rl)(mux)
+	// Aplica middleware; /metrics e os health checks ficam fora do rate limit
+	root := http.NewServeMux()
+	root.Handle("/metrics", m.Handler())
+	root.HandleFunc("/livez", checker.Live)
+	root.HandleFunc("/readyz", checker.Ready)
+	root.HandleFunc("/health", checker.Ready)
+	// API de verificação para clientes que não são Go; também fica fora do rate
+	// limit por IP, já que consome o limite das chaves informadas
+	if cfg.CheckToken != "" {
+		checkAPI := check.NewHandler(rl, cfg.CheckToken)
+		root.Handle("/v1/check", checkAPI)
+		root.Handle("/v1/check/", checkAPI)
+	}
+	root.Handle("/", ratelimit.Middleware(rl, ratelimit.WithTrustedProxies(trusted))(app))
 
-	addr := fmt.Sprintf(":%s", cfg.ServerPort)
-	log.Printf("Server starting on %s", addr)
-	log.Printf("IP Rate Limit: %d req/s", cfg.IpLimitRps)
-	log.Printf("Token Rate Limit: %d req/s", cfg.TokenLimitRps)
+	servers := []server.Server{
+		server.New(fmt.Sprintf(":%s", cfg.ServerPort), tracing.Middleware(root), cfg),
+	}
+
+	// API de administração em porta separada, desativada sem ADMIN_TOKEN
+	if cfg.AdminToken != "" {
+		servers = append(servers, server.New(fmt.Sprintf(":%s", cfg.AdminPort), admin.NewHandler(rl, cfg.AdminToken, adminOpts...), cfg))
+	} else {
+		slog.Warn("ADMIN_TOKEN not set, admin API disabled")
+	}
+
+	// Backends de decisão para o Envoy, cada um em sua porta e desativados por padrão
+	if cfg.ExtAuthzPort != "" {
+		servers = append(servers, server.New(fmt.Sprintf(":%s", cfg.ExtAuthzPort), tracing.Middleware(envoy.NewAuthzHandler(rl, envoy.WithHeaderPolicies(cfg.ExtAuthzPolicies...), envoy.WithTrustedProxies(trusted))), cfg))
+	}
+	if cfg.RLSPort != "" {
+		rls, err := envoy.NewRateLimitService(rl, cfg.RLSDescriptorRules)
+		if err != nil {
+			return fmt.Errorf("invalid config: RLS_DESCRIPTORS: %w", err)
+		}
+		grpcServer := grpc.NewServer()
+		rlsv3.RegisterRateLimitServiceServer(grpcServer, rls)
+		servers = append(servers, server.NewGRPC(fmt.Sprintf(":%s", cfg.RLSPort), grpcServer))
+	}
+
+	slog.Info("Server starting",
+		slog.String("port", cfg.ServerPort),
+		slog.String("storage_backend", cfg.StorageBackend),
+		slog.Bool("proxy", proxied),
+		slog.String("extauthz_port", cfg.ExtAuthzPort),
+		slog.String("rls_port", cfg.RLSPort),
+		slog.Bool("check_api", cfg.CheckToken != ""),
+		slog.Int("ip_limit_rps", cfg.IpLimitRps),
+		slog.Int("token_limit_rps", cfg.TokenLimitRps),
+	)
+
+	// a readiness falha assim que o sinal chega; os servidores só param depois de
+	// SHUTDOWN_DRAIN_DELAY, para os load balancers deixarem de enviar tráfego. A
+	// espera consome o mesmo prazo de SHUTDOWN_TIMEOUT.
+	serveCtx, stopServing := context.WithCancel(context.Background())
+	defer stopServing()
+	context.AfterFunc(ctx, func() {
+		checker.Drain()
+		shutdown.Sleep(cfg.ShutdownDrainDelay)
+		stopServing()
+	})
+
+	return server.Serve(serveCtx, shutdown, servers...)
+}
 
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		log.Fatalf("Server failed: %v", err)
+// closeStage executa uma etapa do shutdown dentro do prazo único do encerramento,
+// registrando falhas
+func closeStage(shutdown *server.Deadline, name string, fn func(context.Context) error) {
+	if err := fn(shutdown.Context()); err != nil {
+		slog.Error("Failed to shut down "+name, slog.Any("error", err))
+		return
 	}
+	slog.Debug("Shut down " + name)
 }
@@ -0,0 +1,293 @@
+// Package admin expõe a API HTTP de inspeção e gerenciamento do estado do
+// limiter. Deve ser servida em uma porta separada da aplicação.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/offenders"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+type Handler struct {
+	rl        *limiter.RateLimiter
+	token     string
+	mux       *http.ServeMux
+	audit     *audit.Logger
+	hasher    audit.Hasher
+	offenders offenders.Tracker
+}
+
+type Option func(*Handler)
+
+// WithAuditLog registra os bloqueios manuais no audit log
+func WithAuditLog(a *audit.Logger) Option {
+	return func(h *Handler) {
+		h.audit = a
+	}
+}
+
+// WithKeyHasher define o hash dos IPs/tokens no log; deve ser o mesmo do audit log
+func WithKeyHasher(hasher audit.Hasher) Option {
+	return func(h *Handler) {
+		h.hasher = hasher
+	}
+}
+
+// WithOffenders habilita GET /v1/offenders com o top de IPs/tokens
+func WithOffenders(t offenders.Tracker) Option {
+	return func(h *Handler) {
+		h.offenders = t
+	}
+}
+
+// NewHandler cria a API de administração protegida por "Authorization: Bearer <token>"
+func NewHandler(rl *limiter.RateLimiter, token string, opts ...Option) *Handler {
+	h := &Handler{
+		rl:     rl,
+		token:  token,
+		mux:    http.NewServeMux(),
+		hasher: audit.NewRandomHasher(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("GET /v1/blocked", h.listBlocked)
+	h.mux.HandleFunc("GET /v1/keys/{kind}/{id}", h.getState)
+	h.mux.HandleFunc("POST /v1/keys/{kind}/{id}/block", h.block)
+	h.mux.HandleFunc("DELETE /v1/keys/{kind}/{id}/block", h.unblock)
+	h.mux.HandleFunc("POST /v1/keys/{kind}/{id}/reset", h.reset)
+	if h.offenders != nil {
+		h.mux.HandleFunc("GET /v1/offenders", h.topOffenders)
+	}
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ratelimiter-admin"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) listBlocked(w http.ResponseWriter, r *http.Request) {
+	blocked, err := h.rl.ListBlocked(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewBlockedList(blocked, time.Now()))
+}
+
+func (h *Handler) getState(w http.ResponseWriter, r *http.Request) {
+	kind, id := r.PathValue("kind"), r.PathValue("id")
+
+	state, err := h.rl.State(r.Context(), kind, id)
+	if err != nil {
+		writeLimiterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewKeyState(kind, id, state))
+}
+
+func (h *Handler) block(w http.ResponseWriter, r *http.Request) {
+	kind, id := r.PathValue("kind"), r.PathValue("id")
+
+	var req dto.AdminBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		writeError(w, http.StatusBadRequest, "duration must be a positive Go duration, e.g. \"5m\"")
+		return
+	}
+
+	if err := h.rl.Block(r.Context(), kind, id, duration); err != nil {
+		writeLimiterError(w, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin blocked key",
+		slog.String("key_type", kind),
+		slog.String("key_hash", h.hasher.HashKey(id)),
+		slog.Duration("duration", duration),
+		slog.String("reason", req.Reason),
+	)
+	if h.audit != nil {
+		h.audit.ManualBlock(r.Context(), kind, id, duration, req.Reason)
+	}
+
+	h.writeState(w, r, kind, id)
+}
+
+func (h *Handler) unblock(w http.ResponseWriter, r *http.Request) {
+	kind, id := r.PathValue("kind"), r.PathValue("id")
+
+	if err := h.rl.Unblock(r.Context(), kind, id); err != nil {
+		writeLimiterError(w, err)
+		return
+	}
+
+	h.writeState(w, r, kind, id)
+}
+
+func (h *Handler) reset(w http.ResponseWriter, r *http.Request) {
+	kind, id := r.PathValue("kind"), r.PathValue("id")
+
+	if err := h.rl.Reset(r.Context(), kind, id); err != nil {
+		writeLimiterError(w, err)
+		return
+	}
+
+	h.writeState(w, r, kind, id)
+}
+
+func (h *Handler) topOffenders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	metric := offenders.MetricDenials
+	if v := query.Get("metric"); v != "" {
+		m, err := offenders.ParseMetric(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		metric = m
+	}
+
+	limit := offenders.DefaultTop
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.offenders.Top(r.Context(), metric, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewOffenders(metric, h.offenders.Window(), entries))
+}
+
+// NewOffenders converte o top do tracker para a resposta da API
+func NewOffenders(metric offenders.Metric, window time.Duration, entries []offenders.Entry) dto.AdminOffenders {
+	response := dto.AdminOffenders{
+		Metric:    string(metric),
+		Window:    window.String(),
+		Offenders: make([]dto.AdminOffender, 0, len(entries)),
+	}
+	for i, e := range entries {
+		response.Offenders = append(response.Offenders, dto.AdminOffender{
+			Rank:  i + 1,
+			Kind:  e.Kind,
+			ID:    e.ID,
+			Count: e.Count,
+		})
+	}
+
+	return response
+}
+
+// writeState responde com o estado da chave após uma alteração
+func (h *Handler) writeState(w http.ResponseWriter, r *http.Request, kind, id string) {
+	state, err := h.rl.State(r.Context(), kind, id)
+	if err != nil {
+		writeLimiterError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewKeyState(kind, id, state))
+}
+
+// NewKeyState converte o estado do storage para a resposta da API
+func NewKeyState(kind, id string, state storage.State) dto.AdminKeyState {
+	response := dto.AdminKeyState{
+		Kind:    kind,
+		ID:      id,
+		Count:   state.Count,
+		Blocked: state.Blocked(),
+	}
+
+	if !state.WindowStart.IsZero() {
+		response.WindowStart = &state.WindowStart
+		response.WindowEnd = &state.WindowEnd
+	}
+	if state.Blocked() {
+		response.BlockedUntil = &state.BlockedUntil
+	}
+
+	return response
+}
+
+// NewBlockedList converte a listagem do limiter para a resposta da API
+func NewBlockedList(blocked []limiter.BlockedKey, now time.Time) dto.AdminBlockedList {
+	response := dto.AdminBlockedList{Keys: make([]dto.AdminBlockedKey, 0, len(blocked))}
+	for _, b := range blocked {
+		response.Keys = append(response.Keys, dto.AdminBlockedKey{
+			Kind:         b.Kind,
+			ID:           b.ID,
+			BlockedUntil: b.BlockedUntil,
+			ExpiresIn:    b.BlockedUntil.Sub(now).Round(time.Second).String(),
+		})
+	}
+
+	return response
+}
+
+func writeLimiterError(w http.ResponseWriter, err error) {
+	if errors.Is(err, limiter.ErrUnknownKind) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, dto.ResponseMessage{Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,199 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/offenders"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+const adminToken = "secret"
+
+func setupAdmin(t *testing.T) (*httptest.Server, *limiter.RateLimiter) {
+	t.Helper()
+
+	cfg := &config.Config{
+		IpLimitRps:         3,
+		IpBlockDuration:    time.Minute,
+		TokenLimitRps:      4,
+		TokenBlockDuration: time.Minute,
+		KeyPrefix:          "ratelimiter",
+	}
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := limiterconfig.New(store, cfg)
+
+	server := httptest.NewServer(NewHandler(rl, adminToken))
+	t.Cleanup(server.Close)
+
+	return server, rl
+}
+
+func doAdminRequest(t *testing.T, server *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("failed to decode response body: %v", err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	server, _ := setupAdmin(t)
+
+	for _, auth := range []string{"", "Bearer wrong", adminToken} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/blocked", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("authorization %q: expected status %d, got %d", auth, http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+}
+
+func TestAdmin_ListsBlockedKeys(t *testing.T) {
+	server, rl := setupAdmin(t)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		rl.Allow(ctx, "1.2.3.4", "")
+	}
+
+	var body dto.AdminBlockedList
+	if status := doAdminRequest(t, server, http.MethodGet, "/v1/blocked", "", &body); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if len(body.Keys) != 1 || body.Keys[0].Kind != limiter.KindIP || body.Keys[0].ID != "1.2.3.4" {
+		t.Errorf("expected ip 1.2.3.4 to be listed as blocked, got %+v", body.Keys)
+	}
+}
+
+func TestAdmin_BlockInspectAndUnblock(t *testing.T) {
+	server, rl := setupAdmin(t)
+	ctx := context.Background()
+
+	var state dto.AdminKeyState
+	status := doAdminRequest(t, server, http.MethodPost, "/v1/keys/token/abc/block", `{"duration":"10m"}`, &state)
+	if status != http.StatusOK || !state.Blocked {
+		t.Fatalf("expected token to be blocked, got status %d and state %+v", status, state)
+	}
+
+	if allowed, _ := rl.Allow(ctx, "1.2.3.4", "abc"); allowed {
+		t.Error("expected manually blocked token to be denied")
+	}
+
+	status = doAdminRequest(t, server, http.MethodDelete, "/v1/keys/token/abc/block", "", &state)
+	if status != http.StatusOK || state.Blocked {
+		t.Fatalf("expected token to be unblocked, got status %d and state %+v", status, state)
+	}
+
+	if allowed, _ := rl.Allow(ctx, "1.2.3.4", "abc"); !allowed {
+		t.Error("expected unblocked token to be allowed")
+	}
+
+	status = doAdminRequest(t, server, http.MethodGet, "/v1/keys/token/abc", "", &state)
+	if status != http.StatusOK || state.Count != 1 {
+		t.Errorf("expected count 1, got status %d and state %+v", status, state)
+	}
+}
+
+func TestAdmin_ResetClearsCounterAndBlock(t *testing.T) {
+	server, rl := setupAdmin(t)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		rl.Allow(ctx, "1.2.3.4", "")
+	}
+
+	var state dto.AdminKeyState
+	status := doAdminRequest(t, server, http.MethodPost, "/v1/keys/ip/1.2.3.4/reset", "", &state)
+	if status != http.StatusOK || state.Blocked || state.Count != 0 {
+		t.Errorf("expected clean state after reset, got status %d and state %+v", status, state)
+	}
+}
+
+func TestAdmin_RejectsInvalidInput(t *testing.T) {
+	server, _ := setupAdmin(t)
+
+	if status := doAdminRequest(t, server, http.MethodGet, "/v1/keys/user/abc", "", nil); status != http.StatusNotFound {
+		t.Errorf("unknown kind: expected status %d, got %d", http.StatusNotFound, status)
+	}
+
+	if status := doAdminRequest(t, server, http.MethodPost, "/v1/keys/ip/1.2.3.4/block", `{"duration":"forever"}`, nil); status != http.StatusBadRequest {
+		t.Errorf("invalid duration: expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestAdmin_TopOffenders(t *testing.T) {
+	cfg := &config.Config{IpLimitRps: 2, IpBlockDuration: time.Minute, TokenLimitRps: 2, TokenBlockDuration: time.Minute}
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	tracker := offenders.NewLocal()
+	rl := limiterconfig.New(store, cfg, limiter.WithObserver(tracker))
+
+	server := httptest.NewServer(NewHandler(rl, adminToken, WithOffenders(tracker)))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		rl.Allow(ctx, "1.2.3.4", "")
+	}
+	for i := 0; i < 4; i++ {
+		rl.Allow(ctx, "5.6.7.8", "")
+	}
+
+	var body dto.AdminOffenders
+	if status := doAdminRequest(t, server, http.MethodGet, "/v1/offenders?limit=1", "", &body); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	if body.Metric != "denials" || body.Window != "5m0s" {
+		t.Errorf("unexpected metric/window: %+v", body)
+	}
+	want := dto.AdminOffender{Rank: 1, Kind: limiter.KindIP, ID: "1.2.3.4", Count: 4}
+	if len(body.Offenders) != 1 || body.Offenders[0] != want {
+		t.Errorf("expected %+v, got %+v", want, body.Offenders)
+	}
+
+	if status := doAdminRequest(t, server, http.MethodGet, "/v1/offenders?metric=bytes", "", nil); status != http.StatusBadRequest {
+		t.Errorf("unknown metric: expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
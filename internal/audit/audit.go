@@ -0,0 +1,138 @@
+// Package audit grava um log separado com cada evento de bloqueio, para
+// investigação de abuso sem registrar todas as requisições permitidas.
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/requestinfo"
+)
+
+type Logger struct {
+	logger *slog.Logger
+	closer io.Closer
+	hasher Hasher
+}
+
+// New abre o destino do audit log: "stdout", "stderr" ou o caminho de um arquivo
+// (aberto em modo append). O formato é sempre JSON.
+func New(dest string, hasher Hasher) (*Logger, error) {
+	var (
+		w      io.Writer
+		closer io.Closer
+	)
+
+	switch dest {
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		w, closer = f, f
+	}
+
+	return NewWithWriter(w, closer, hasher), nil
+}
+
+// NewWithWriter cria o audit log sobre um writer qualquer; closer pode ser nil
+func NewWithWriter(w io.Writer, closer io.Closer, hasher Hasher) *Logger {
+	return &Logger{
+		logger: slog.New(slog.NewJSONHandler(w, nil)).With(slog.String("stream", "audit")),
+		closer: closer,
+		hasher: hasher,
+	}
+}
+
+// Hasher identifica um IP/token no audit log e nos eventos sem expor o valor
+// original. Usa HMAC-SHA256 com um segredo: sem ele, um hash de IP pode ser
+// revertido testando todos os IPs. Trocar o segredo muda todos os hashes, e os
+// registros anteriores deixam de ser correlacionáveis com os novos.
+type Hasher struct {
+	key []byte
+}
+
+func NewHasher(key []byte) Hasher {
+	return Hasher{key: key}
+}
+
+// NewRandomHasher usa um segredo aleatório: os hashes mudam a cada reinício e
+// diferem entre instâncias
+func NewRandomHasher() Hasher {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return Hasher{key: key}
+}
+
+func (h Hasher) HashKey(id string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// ObserveDecision registra apenas as decisões que bloquearam a chave
+func (a *Logger) ObserveDecision(ctx context.Context, d limiter.Decision) {
+	if d.Outcome != limiter.OutcomeDenied {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("event", "blocked"),
+		slog.String("source", "limiter"),
+		slog.String("key_type", d.Kind),
+		slog.String("key_hash", a.hasher.HashKey(d.ID)),
+		slog.String("policy", d.Policy),
+		slog.Int("limit", d.Limit),
+		slog.Int64("count", d.Count),
+		slog.Duration("duration", d.BlockDuration),
+		slog.Time("blocked_until", d.BlockedUntil),
+	}
+	attrs = append(attrs, requestAttrs(ctx)...)
+
+	a.logger.LogAttrs(ctx, slog.LevelInfo, "key blocked", attrs...)
+}
+
+// ManualBlock registra um bloqueio feito por um operador
+func (a *Logger) ManualBlock(ctx context.Context, kind, id string, duration time.Duration, reason string) {
+	a.logger.LogAttrs(ctx, slog.LevelInfo, "key blocked",
+		slog.String("event", "blocked"),
+		slog.String("source", "admin"),
+		slog.String("key_type", kind),
+		slog.String("key_hash", a.hasher.HashKey(id)),
+		slog.Duration("duration", duration),
+		slog.String("reason", reason),
+	)
+}
+
+func (a *Logger) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+func requestAttrs(ctx context.Context) []slog.Attr {
+	info, ok := requestinfo.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return []slog.Attr{
+		slog.String("request_id", info.RequestID),
+		slog.String("method", info.Method),
+		slog.String("route", info.Route),
+	}
+}
@@ -0,0 +1,88 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func TestAudit_RecordsOnlyBlockEvents(t *testing.T) {
+	var buf bytes.Buffer
+	hasher := audit.NewHasher([]byte("test-secret"))
+	auditLog := audit.NewWithWriter(&buf, nil, hasher)
+
+	cfg := &config.Config{IpLimitRps: 2, IpBlockDuration: time.Minute, TokenLimitRps: 2, TokenBlockDuration: time.Minute}
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := limiterconfig.New(store, cfg, limiter.WithObserver(auditLog))
+	handler := middleware.RateLimiter(rl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.Header.Set("API_KEY", "secret-token")
+		req.Header.Set("X-Request-ID", "req-"+string(rune('a'+i)))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one audit entry for the block event, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	want := map[string]any{
+		"stream":     "audit",
+		"event":      "blocked",
+		"key_type":   limiter.KindToken,
+		"key_hash":   hasher.HashKey("secret-token"),
+		"limit":      float64(2),
+		"route":      "/orders",
+		"request_id": "req-c",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("field %s: expected %v, got %v", key, value, entry[key])
+		}
+	}
+
+	if strings.Contains(buf.String(), "secret-token") {
+		t.Error("expected the raw token never to be written to the audit log")
+	}
+}
+
+func TestHasher_DependsOnSecret(t *testing.T) {
+	a := audit.NewHasher([]byte("secret-a"))
+	b := audit.NewHasher([]byte("secret-b"))
+
+	if a.HashKey("203.0.113.7") != audit.NewHasher([]byte("secret-a")).HashKey("203.0.113.7") {
+		t.Error("expected the same secret to produce the same hash")
+	}
+	if a.HashKey("203.0.113.7") == b.HashKey("203.0.113.7") {
+		t.Error("expected different secrets to produce different hashes")
+	}
+}
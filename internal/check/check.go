@@ -0,0 +1,221 @@
+// Package check expõe a decisão do limiter como API HTTP JSON para clientes que
+// não são Go: "a chave X pode consumir N unidades da política P?".
+package check
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+)
+
+const (
+	// MaxBatch é o número máximo de verificações em POST /v1/check/batch
+	MaxBatch = 100
+
+	maxBodyBytes = 1 << 20
+)
+
+type Handler struct {
+	rl    *limiter.RateLimiter
+	token string
+	mux   *http.ServeMux
+	clock clock.Clock
+}
+
+type Option func(*Handler)
+
+// WithClock substitui o relógio usado para calcular retry_after; em testes deve
+// ser o mesmo passado ao limiter
+func WithClock(c clock.Clock) Option {
+	return func(h *Handler) {
+		h.clock = c
+	}
+}
+
+// NewHandler cria a API de verificação protegida por "Authorization: Bearer <token>"
+func NewHandler(rl *limiter.RateLimiter, token string, opts ...Option) *Handler {
+	h := &Handler{
+		rl:    rl,
+		token: token,
+		mux:   http.NewServeMux(),
+		clock: clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("POST /v1/check", h.check)
+	h.mux.HandleFunc("POST /v1/check/batch", h.batch)
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="ratelimiter-check"`)
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) check(w http.ResponseWriter, r *http.Request) {
+	var req dto.CheckRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := h.validate(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := h.decide(r.Context(), req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "check failed", slog.String("policy", req.Policy), slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "rate limiter unavailable")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batch valida todas as verificações antes de consumir qualquer uma, mas não é
+// atômico: as permitidas consomem o limite mesmo que outra seja negada
+func (h *Handler) batch(w http.ResponseWriter, r *http.Request) {
+	var req dto.CheckBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if len(req.Checks) == 0 || len(req.Checks) > MaxBatch {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("checks must have between 1 and %d items", MaxBatch))
+		return
+	}
+
+	for i := range req.Checks {
+		req.Checks[i].DryRun = req.Checks[i].DryRun || req.DryRun
+		if err := h.validate(&req.Checks[i]); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("checks[%d]: %v", i, err))
+			return
+		}
+	}
+
+	resp := dto.CheckBatchResponse{Allowed: true, Results: make([]dto.CheckResponse, len(req.Checks))}
+	for i, c := range req.Checks {
+		result, err := h.decide(r.Context(), c)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "check failed", slog.String("policy", c.Policy), slog.Any("error", err))
+			result = dto.CheckResponse{Policy: c.Policy, Cost: c.Cost, DryRun: c.DryRun, Error: "rate limiter unavailable"}
+		}
+
+		resp.Allowed = resp.Allowed && result.Allowed
+		resp.Results[i] = result
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validate aplica o custo padrão e confere política, chave e custo
+func (h *Handler) validate(req *dto.CheckRequest) error {
+	if req.Cost == 0 {
+		req.Cost = 1
+	}
+
+	switch {
+	case req.Policy == "":
+		return errors.New("policy is required")
+	case req.Key == "":
+		return errors.New("key is required")
+	case req.Cost < 0:
+		return limiter.ErrInvalidCost
+	}
+
+	if _, ok := h.rl.Policy(req.Policy); !ok {
+		return fmt.Errorf("%w: %q", limiter.ErrUnknownKind, req.Policy)
+	}
+	return nil
+}
+
+func (h *Handler) decide(ctx context.Context, req dto.CheckRequest) (dto.CheckResponse, error) {
+	var (
+		d   limiter.Decision
+		err error
+	)
+	if req.DryRun {
+		d, err = h.rl.Peek(ctx, req.Policy, req.Key, req.Cost)
+	} else {
+		d, err = h.rl.DecidePolicy(ctx, req.Policy, req.Key, req.Cost)
+	}
+	if err != nil {
+		return dto.CheckResponse{}, err
+	}
+
+	resp := dto.CheckResponse{
+		Policy:    d.Policy,
+		Allowed:   d.Allowed(),
+		Outcome:   string(d.Outcome),
+		Limit:     d.Limit,
+		Remaining: d.Remaining,
+		Cost:      d.Cost,
+		DryRun:    req.DryRun,
+	}
+
+	// o fim da janela/bloqueio não faz parte da decisão; sem ele (ex.: fail-open
+	// com o storage fora) a resposta só não traz reset_at
+	state, err := h.rl.State(ctx, d.Policy, d.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get reset time", slog.Any("error", err))
+		return resp, nil
+	}
+
+	reset := state.WindowEnd
+	if state.Blocked() {
+		reset = state.BlockedUntil
+	}
+	if !reset.IsZero() {
+		resp.ResetAt = &reset
+		if !resp.Allowed {
+			resp.RetryAfter = max(int(math.Ceil(reset.Sub(h.clock.Now()).Seconds())), 1)
+		}
+	}
+
+	return resp, nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, dto.ResponseMessage{Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
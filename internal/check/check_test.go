@@ -0,0 +1,160 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+const testToken = "secret"
+
+func newTestHandler(t *testing.T) (*Handler, *clock.Fake) {
+	t.Helper()
+
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	store := storage.NewMemoryStorage(storage.WithMemoryClock(clk))
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{
+		IpLimitRps:         10,
+		IpBlockDuration:    time.Minute,
+		TokenLimitRps:      100,
+		TokenBlockDuration: time.Minute,
+		KeyPrefix:          "test",
+	}
+	rl := limiterconfig.New(store, cfg, limiter.WithClock(clk),
+		limiter.WithPolicy(limiter.Policy{Name: "export", Limit: 10, Window: time.Minute, BlockDuration: 5 * time.Minute}))
+
+	return NewHandler(rl, testToken, WithClock(clk)), clk
+}
+
+func post(h http.Handler, path string, body any) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return v
+}
+
+func TestCheck_ConsumesAndReportsRetryAfter(t *testing.T) {
+	h, clk := newTestHandler(t)
+
+	rec := post(h, "/v1/check", dto.CheckRequest{Policy: "export", Key: "user-1", Cost: 8})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	resp := decode[dto.CheckResponse](t, rec)
+	if !resp.Allowed || resp.Remaining != 2 || resp.Cost != 8 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.ResetAt == nil || !resp.ResetAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Errorf("expected reset at the end of the window, got %v", resp.ResetAt)
+	}
+
+	// dry-run mostra que o custo 3 seria negado, sem consumir nem bloquear
+	resp = decode[dto.CheckResponse](t, post(h, "/v1/check", dto.CheckRequest{Policy: "export", Key: "user-1", Cost: 3, DryRun: true}))
+	if resp.Allowed || !resp.DryRun || resp.RetryAfter != 60 {
+		t.Errorf("unexpected dry-run response: %+v", resp)
+	}
+
+	resp = decode[dto.CheckResponse](t, post(h, "/v1/check", dto.CheckRequest{Policy: "export", Key: "user-1", Cost: 2}))
+	if !resp.Allowed || resp.Remaining != 0 {
+		t.Errorf("expected the dry-run not to consume, got %+v", resp)
+	}
+
+	clk.Advance(10 * time.Second)
+	resp = decode[dto.CheckResponse](t, post(h, "/v1/check", dto.CheckRequest{Policy: "export", Key: "user-1"}))
+	if resp.Allowed || resp.Outcome != string(limiter.OutcomeDenied) || resp.RetryAfter != 300 {
+		t.Errorf("expected denial with retry after the block, got %+v", resp)
+	}
+}
+
+func TestCheck_RejectsInvalidRequests(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	invalid := []dto.CheckRequest{
+		{Key: "user-1"},
+		{Policy: "export"},
+		{Policy: "export", Key: "user-1", Cost: -1},
+		{Policy: "unknown", Key: "user-1"},
+	}
+	for _, req := range invalid {
+		if rec := post(h, "/v1/check", req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%+v: expected 400, got %d", req, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/check", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+}
+
+func TestCheckBatch(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	rec := post(h, "/v1/check/batch", dto.CheckBatchRequest{Checks: []dto.CheckRequest{
+		{Policy: "export", Key: "user-1", Cost: 4},
+		{Policy: "export", Key: "user-2", Cost: 11},
+		{Policy: "ip", Key: "10.0.0.1"},
+	}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	resp := decode[dto.CheckBatchResponse](t, rec)
+	if resp.Allowed {
+		t.Error("expected the batch not to be allowed")
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(resp.Results))
+	}
+	if !resp.Results[0].Allowed || resp.Results[1].Allowed || !resp.Results[2].Allowed {
+		t.Errorf("unexpected results: %+v", resp.Results)
+	}
+
+	// uma verificação inválida rejeita o lote inteiro antes de consumir
+	rec = post(h, "/v1/check/batch", dto.CheckBatchRequest{Checks: []dto.CheckRequest{
+		{Policy: "export", Key: "user-1", Cost: 4},
+		{Policy: "unknown", Key: "user-1"},
+	}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+
+	resp = decode[dto.CheckBatchResponse](t, post(h, "/v1/check/batch", dto.CheckBatchRequest{
+		DryRun: true,
+		Checks: []dto.CheckRequest{{Policy: "export", Key: "user-1", Cost: 6}},
+	}))
+	if !resp.Allowed || resp.Results[0].Remaining != 0 || !resp.Results[0].DryRun {
+		t.Errorf("expected user-1 to have only its first check consumed, got %+v", resp.Results[0])
+	}
+
+	if rec := post(h, "/v1/check/batch", dto.CheckBatchRequest{}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty batch, got %d", rec.Code)
+	}
+}
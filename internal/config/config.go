@@ -1,8 +1,13 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +20,71 @@ type Config struct {
 	RedisPassword      string
 	RedisDB            int
 	ServerPort         string
+	KeyPrefix          string
+	KeyNamespace       string
+	StorageBackend     string
+	BoltPath           string
+	AdminPort          string
+	AdminToken         string
+	CheckToken         string
+	FailOpen           bool
+	LogLevel           string
+	LogFormat          string
+	AuditLog           string
+	// Segredo do HMAC dos key_hash; vazio usa um segredo aleatório por processo
+	AuditHashKey string
+	// Tracing OTLP; desativado com endpoint vazio
+	TracingEndpoint    string
+	TracingServiceName string
+	TracingSampleRatio float64
+	// Servidor HTTP; ShutdownTimeout limita a espera pelas requisições em andamento
+	// e ShutdownDrainDelay é a parte dele em que a readiness já falha mas os
+	// servidores continuam atendendo
+	ServerReadTimeout       time.Duration
+	ServerReadHeaderTimeout time.Duration
+	ServerWriteTimeout      time.Duration
+	ServerIdleTimeout       time.Duration
+	ServerMaxHeaderBytes    int
+	ShutdownTimeout         time.Duration
+	ShutdownDrainDelay      time.Duration
+	// Readiness: tempo limite e latência máxima aceitável do ping no storage
+	ReadinessTimeout    time.Duration
+	ReadinessMaxLatency time.Duration
+	// Políticas adicionais "nome=limite/janela[/bloqueio]", separadas por vírgula
+	Policies []string
+	// IPs/CIDRs dos proxies cujos headers X-Forwarded-For/X-Real-IP são confiáveis
+	TrustedProxies []string
+	// Decisões para o Envoy: ext_authz HTTP e Rate Limit Service gRPC; porta vazia desativa
+	ExtAuthzPort string
+	// Políticas que o ext_authz aceita em X-RateLimit-Policy; vazio recusa o header
+	ExtAuthzPolicies   []string
+	RLSPort            string
+	RLSDescriptorRules []string
+	// Modo reverse proxy: upstream padrão e rotas "/prefixo=url" separadas por vírgula
+	UpstreamURL     string
+	UpstreamRoutes  []string
+	UpstreamTimeout time.Duration
+	// Notificações de eventos; sinks separados por vírgula (stdout, webhook, redis)
+	EventSinks          []string
+	EventWebhookURL     string
+	EventWebhookSecret  string
+	EventWebhookRetries int
+	EventRedisStream    string
+	EventRedisMaxLen    int64
+	EventThreshold      float64
+	// Top offenders: local, redis (agregado entre instâncias) ou off
+	OffendersMode         string
+	OffendersWindow       time.Duration
+	OffendersCapacity     int
+	OffendersSyncInterval time.Duration
+	// Intervalo em que o gauge de chaves bloqueadas reaproveita a última listagem; 0 lista a cada scrape
+	MetricsBlockedKeysTTL time.Duration
+	// Opções dos backends; validadas por backends.Validate
+	BoltCompactionInterval time.Duration
+	BoltBatchDelay         time.Duration
+	MemoryMaxEntries       int
+	MemoryCleanupInterval  time.Duration
+	MemoryShards           int
 }
 
 func Load() (*Config, error) {
@@ -43,6 +113,126 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	serverReadTimeout, err := time.ParseDuration(getEnv("SERVER_READ_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, err
+	}
+
+	serverReadHeaderTimeout, err := time.ParseDuration(getEnv("SERVER_READ_HEADER_TIMEOUT", "5s"))
+	if err != nil {
+		return nil, err
+	}
+
+	serverWriteTimeout, err := time.ParseDuration(getEnv("SERVER_WRITE_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
+	serverIdleTimeout, err := time.ParseDuration(getEnv("SERVER_IDLE_TIMEOUT", "120s"))
+	if err != nil {
+		return nil, err
+	}
+
+	serverMaxHeaderBytes, err := strconv.Atoi(getEnv("SERVER_MAX_HEADER_BYTES", "1048576"))
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownTimeout, err := time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownDrainDelay, err := time.ParseDuration(getEnv("SHUTDOWN_DRAIN_DELAY", "5s"))
+	if err != nil {
+		return nil, err
+	}
+
+	readinessTimeout, err := time.ParseDuration(getEnv("READINESS_TIMEOUT", "2s"))
+	if err != nil {
+		return nil, err
+	}
+
+	readinessMaxLatency, err := time.ParseDuration(getEnv("READINESS_MAX_LATENCY", "250ms"))
+	if err != nil {
+		return nil, err
+	}
+
+	upstreamTimeout, err := time.ParseDuration(getEnv("UPSTREAM_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
+	boltCompactionInterval, err := time.ParseDuration(getEnv("BOLT_COMPACTION_INTERVAL", "1m"))
+	if err != nil {
+		return nil, err
+	}
+
+	boltBatchDelay, err := time.ParseDuration(getEnv("BOLT_BATCH_DELAY", "1ms"))
+	if err != nil {
+		return nil, err
+	}
+
+	memoryMaxEntries, err := strconv.Atoi(getEnv("MEMORY_MAX_ENTRIES", "100000"))
+	if err != nil {
+		return nil, err
+	}
+
+	memoryCleanupInterval, err := time.ParseDuration(getEnv("MEMORY_CLEANUP_INTERVAL", "1m"))
+	if err != nil {
+		return nil, err
+	}
+
+	memoryShards, err := strconv.Atoi(getEnv("MEMORY_SHARDS", "32"))
+	if err != nil {
+		return nil, err
+	}
+
+	failOpen, err := strconv.ParseBool(getEnv("FAIL_OPEN", "false"))
+	if err != nil {
+		return nil, err
+	}
+
+	tracingSampleRatio, err := strconv.ParseFloat(getEnv("OTEL_TRACES_SAMPLER_RATIO", "1"), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	eventWebhookRetries, err := strconv.Atoi(getEnv("EVENT_WEBHOOK_RETRIES", "3"))
+	if err != nil {
+		return nil, err
+	}
+
+	eventRedisMaxLen, err := strconv.ParseInt(getEnv("EVENT_REDIS_MAXLEN", "10000"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	eventThreshold, err := strconv.ParseFloat(getEnv("EVENT_THRESHOLD", "0.8"), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	offendersWindow, err := time.ParseDuration(getEnv("OFFENDERS_WINDOW", "5m"))
+	if err != nil {
+		return nil, err
+	}
+
+	offendersCapacity, err := strconv.Atoi(getEnv("OFFENDERS_CAPACITY", "100"))
+	if err != nil {
+		return nil, err
+	}
+
+	offendersSyncInterval, err := time.ParseDuration(getEnv("OFFENDERS_SYNC_INTERVAL", "10s"))
+	if err != nil {
+		return nil, err
+	}
+
+	metricsBlockedKeysTTL, err := time.ParseDuration(getEnv("METRICS_BLOCKED_KEYS_TTL", "30s"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		IpLimitRps:         ipLimit,
 		IpBlockDuration:    ipBlockDuration,
@@ -52,9 +242,195 @@ func Load() (*Config, error) {
 		RedisPassword:      getEnv("REDIS_PASSWORD", ""),
 		RedisDB:            redisDB,
 		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		KeyPrefix:          getEnv("KEY_PREFIX", "ratelimiter"),
+		KeyNamespace:       getEnv("KEY_NAMESPACE", ""),
+		StorageBackend:     getEnv("STORAGE_BACKEND", "redis"),
+		BoltPath:           getEnv("BOLT_PATH", "ratelimiter.db"),
+		AdminPort:          getEnv("ADMIN_PORT", "9090"),
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
+		CheckToken:         getEnv("CHECK_TOKEN", ""),
+		FailOpen:           failOpen,
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		LogFormat:          getEnv("LOG_FORMAT", "json"),
+		AuditLog:           getEnv("AUDIT_LOG", "stdout"),
+		AuditHashKey:       getEnv("AUDIT_HASH_KEY", ""),
+		TracingEndpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "ratelimiter"),
+		TracingSampleRatio: tracingSampleRatio,
+
+		ServerReadTimeout:       serverReadTimeout,
+		ServerReadHeaderTimeout: serverReadHeaderTimeout,
+		ServerWriteTimeout:      serverWriteTimeout,
+		ServerIdleTimeout:       serverIdleTimeout,
+		ServerMaxHeaderBytes:    serverMaxHeaderBytes,
+		ShutdownTimeout:         shutdownTimeout,
+		ShutdownDrainDelay:      shutdownDrainDelay,
+
+		ReadinessTimeout:    readinessTimeout,
+		ReadinessMaxLatency: readinessMaxLatency,
+
+		Policies: splitList(getEnv("POLICIES", "")),
+
+		TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
+
+		ExtAuthzPort:       getEnv("EXTAUTHZ_PORT", ""),
+		ExtAuthzPolicies:   splitList(getEnv("EXTAUTHZ_POLICIES", "")),
+		RLSPort:            getEnv("RLS_PORT", ""),
+		RLSDescriptorRules: splitList(getEnv("RLS_DESCRIPTORS", "remote_address=ip,api_key=token")),
+
+		UpstreamURL:     getEnv("UPSTREAM_URL", ""),
+		UpstreamRoutes:  splitList(getEnv("UPSTREAM_ROUTES", "")),
+		UpstreamTimeout: upstreamTimeout,
+
+		EventSinks:          splitList(getEnv("EVENT_SINKS", "")),
+		EventWebhookURL:     getEnv("EVENT_WEBHOOK_URL", ""),
+		EventWebhookSecret:  getEnv("EVENT_WEBHOOK_SECRET", ""),
+		EventWebhookRetries: eventWebhookRetries,
+		EventRedisStream:    getEnv("EVENT_REDIS_STREAM", "ratelimiter:events"),
+		EventRedisMaxLen:    eventRedisMaxLen,
+		EventThreshold:      eventThreshold,
+
+		OffendersMode:         getEnv("OFFENDERS_MODE", "local"),
+		OffendersWindow:       offendersWindow,
+		OffendersCapacity:     offendersCapacity,
+		OffendersSyncInterval: offendersSyncInterval,
+
+		MetricsBlockedKeysTTL: metricsBlockedKeysTTL,
+
+		BoltCompactionInterval: boltCompactionInterval,
+		BoltBatchDelay:         boltBatchDelay,
+		MemoryMaxEntries:       memoryMaxEntries,
+		MemoryCleanupInterval:  memoryCleanupInterval,
+		MemoryShards:           memoryShards,
 	}, nil
 }
 
+// Validate checa as opções gerais; as opções de cada backend são validadas por backends.Validate
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.IpLimitRps <= 0 {
+		errs = append(errs, errors.New("IP_LIMIT_RPS must be positive"))
+	}
+	if c.IpBlockDuration <= 0 {
+		errs = append(errs, errors.New("IP_BLOCK_DURATION must be positive"))
+	}
+	if c.TokenLimitRps <= 0 {
+		errs = append(errs, errors.New("TOKEN_LIMIT_RPS must be positive"))
+	}
+	if c.TokenBlockDuration <= 0 {
+		errs = append(errs, errors.New("TOKEN_BLOCK_DURATION must be positive"))
+	}
+	if err := validatePort(c.ServerPort); err != nil {
+		errs = append(errs, fmt.Errorf("SERVER_PORT: %w", err))
+	}
+	if err := validatePort(c.AdminPort); err != nil {
+		errs = append(errs, fmt.Errorf("ADMIN_PORT: %w", err))
+	}
+	if c.ServerReadTimeout < 0 || c.ServerReadHeaderTimeout < 0 || c.ServerWriteTimeout < 0 || c.ServerIdleTimeout < 0 {
+		errs = append(errs, errors.New("SERVER_*_TIMEOUT must not be negative"))
+	}
+	if c.ServerMaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("SERVER_MAX_HEADER_BYTES must be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.ShutdownDrainDelay < 0 || c.ShutdownDrainDelay >= c.ShutdownTimeout {
+		errs = append(errs, errors.New("SHUTDOWN_DRAIN_DELAY must not be negative and must be less than SHUTDOWN_TIMEOUT"))
+	}
+	if c.ReadinessTimeout <= 0 {
+		errs = append(errs, errors.New("READINESS_TIMEOUT must be positive"))
+	}
+	if c.ReadinessMaxLatency < 0 {
+		errs = append(errs, errors.New("READINESS_MAX_LATENCY must not be negative"))
+	}
+	if c.UpstreamTimeout < 0 {
+		errs = append(errs, errors.New("UPSTREAM_TIMEOUT must not be negative"))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, errors.New("LOG_FORMAT must be json or text"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: invalid level %q", c.LogLevel))
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		errs = append(errs, errors.New("OTEL_TRACES_SAMPLER_RATIO must be between 0 and 1"))
+	}
+	if c.EventThreshold < 0 || c.EventThreshold >= 1 {
+		errs = append(errs, errors.New("EVENT_THRESHOLD must be between 0 and 1"))
+	}
+	if c.EventWebhookRetries < 0 {
+		errs = append(errs, errors.New("EVENT_WEBHOOK_RETRIES must not be negative"))
+	}
+	for _, sink := range c.EventSinks {
+		switch sink {
+		case "stdout", "redis":
+		case "webhook":
+			if u, err := url.Parse(c.EventWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("EVENT_WEBHOOK_URL: invalid url %q", c.EventWebhookURL))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("EVENT_SINKS: unknown sink %q", sink))
+		}
+	}
+	switch c.OffendersMode {
+	case "off", "local", "redis":
+	default:
+		errs = append(errs, fmt.Errorf("OFFENDERS_MODE: unknown mode %q, expected local, redis or off", c.OffendersMode))
+	}
+	if c.OffendersWindow < time.Minute {
+		errs = append(errs, errors.New("OFFENDERS_WINDOW must be at least 1m"))
+	}
+	if c.OffendersCapacity <= 0 {
+		errs = append(errs, errors.New("OFFENDERS_CAPACITY must be positive"))
+	}
+	if c.OffendersSyncInterval <= 0 {
+		errs = append(errs, errors.New("OFFENDERS_SYNC_INTERVAL must be positive"))
+	}
+	if c.MetricsBlockedKeysTTL < 0 {
+		errs = append(errs, errors.New("METRICS_BLOCKED_KEYS_TTL must not be negative"))
+	}
+	for name, port := range map[string]string{"EXTAUTHZ_PORT": c.ExtAuthzPort, "RLS_PORT": c.RLSPort} {
+		if port == "" {
+			continue
+		}
+		if err := validatePort(port); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		} else if port == c.ServerPort || port == c.AdminPort {
+			errs = append(errs, fmt.Errorf("%s must differ from SERVER_PORT and ADMIN_PORT", name))
+		}
+	}
+	if c.ExtAuthzPort != "" && c.ExtAuthzPort == c.RLSPort {
+		errs = append(errs, errors.New("EXTAUTHZ_PORT must differ from RLS_PORT"))
+	}
+	if c.AdminToken != "" && c.AdminPort == c.ServerPort {
+		errs = append(errs, errors.New("ADMIN_PORT must differ from SERVER_PORT"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// splitList separa uma lista por vírgulas, ignorando itens vazios
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type ResponseMessage struct {
 	Message string `json:"message"`
 }
@@ -7,3 +9,84 @@ type ResponseMessage struct {
 type ResponseHealth struct {
 	Status string `json:"status"`
 }
+
+type AdminKeyState struct {
+	Kind         string     `json:"kind"`
+	ID           string     `json:"id"`
+	Count        int64      `json:"count"`
+	WindowStart  *time.Time `json:"window_start,omitempty"`
+	WindowEnd    *time.Time `json:"window_end,omitempty"`
+	Blocked      bool       `json:"blocked"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+type AdminBlockedKey struct {
+	Kind         string    `json:"kind"`
+	ID           string    `json:"id"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	ExpiresIn    string    `json:"expires_in"`
+}
+
+type AdminBlockedList struct {
+	Keys []AdminBlockedKey `json:"keys"`
+}
+
+type AdminBlockRequest struct {
+	Duration string `json:"duration"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type AdminOffender struct {
+	Rank  int    `json:"rank"`
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Count uint64 `json:"count"`
+}
+
+type AdminOffenders struct {
+	Metric    string          `json:"metric"`
+	Window    string          `json:"window"`
+	Offenders []AdminOffender `json:"offenders"`
+}
+
+type DependencyHealth struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type ResponseReadiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyHealth `json:"checks"`
+}
+
+type CheckRequest struct {
+	Policy string `json:"policy"`
+	Key    string `json:"key"`
+	Cost   int    `json:"cost,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+type CheckResponse struct {
+	Policy    string     `json:"policy"`
+	Allowed   bool       `json:"allowed"`
+	Outcome   string     `json:"outcome"`
+	Limit     int        `json:"limit"`
+	Remaining int        `json:"remaining"`
+	Cost      int        `json:"cost"`
+	ResetAt   *time.Time `json:"reset_at,omitempty"`
+	// RetryAfter em segundos, só quando a requisição não foi permitida
+	RetryAfter int    `json:"retry_after,omitempty"`
+	DryRun     bool   `json:"dry_run,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type CheckBatchRequest struct {
+	Checks []CheckRequest `json:"checks"`
+	DryRun bool           `json:"dry_run,omitempty"`
+}
+
+type CheckBatchResponse struct {
+	Allowed bool            `json:"allowed"`
+	Results []CheckResponse `json:"results"`
+}
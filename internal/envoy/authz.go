@@ -0,0 +1,129 @@
+// Package envoy permite usar o limiter como backend de decisão do Envoy, tanto
+// pelo filtro ext_authz em modo HTTP quanto pelo Rate Limit Service (gRPC).
+package envoy
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+)
+
+// Headers que o Envoy pode enviar para escolher uma política nomeada em vez do
+// limite por IP/token. Devem ser definidos pela configuração do Envoy
+// (headers_to_add), nunca repassados do cliente: quem os controla escolhe a
+// política, a chave e o custo.
+const (
+	HeaderPolicy = "X-RateLimit-Policy"
+	HeaderKey    = "X-RateLimit-Key"
+	HeaderCost   = "X-RateLimit-Cost"
+)
+
+type authzOptions struct {
+	policies map[string]bool
+	trusted  middleware.TrustedProxies
+}
+
+type AuthzOption func(*authzOptions)
+
+// WithHeaderPolicies define as políticas que podem ser escolhidas por
+// X-RateLimit-Policy; as demais respondem 403. Sem esta opção, os headers de
+// política são recusados.
+func WithHeaderPolicies(names ...string) AuthzOption {
+	return func(o *authzOptions) {
+		for _, name := range names {
+			o.policies[name] = true
+		}
+	}
+}
+
+// WithTrustedProxies permite usar o X-Forwarded-For das verificações sem
+// x-envoy-external-address quando elas vêm de um dos proxies
+func WithTrustedProxies(trusted middleware.TrustedProxies) AuthzOption {
+	return func(o *authzOptions) {
+		o.trusted = trusted
+	}
+}
+
+// NewAuthzHandler responde às verificações do filtro ext_authz em modo HTTP: 200
+// libera a requisição e 429 a nega, com os headers de limite nos dois casos.
+// Sem X-RateLimit-Policy, a decisão é a mesma do middleware (API_KEY ou IP do
+// cliente, informado pelo Envoy em x-envoy-external-address).
+func NewAuthzHandler(rl *limiter.RateLimiter, opts ...AuthzOption) http.Handler {
+	o := authzOptions{policies: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			decision limiter.Decision
+			err      error
+		)
+
+		if policy := r.Header.Get(HeaderPolicy); policy != "" {
+			if !o.policies[policy] {
+				writeMessage(w, http.StatusForbidden, "policy "+strconv.Quote(policy)+" is not allowed in "+HeaderPolicy)
+				return
+			}
+
+			key := r.Header.Get(HeaderKey)
+			if key == "" {
+				writeMessage(w, http.StatusBadRequest, HeaderKey+" header is required with "+HeaderPolicy)
+				return
+			}
+
+			cost := 1
+			if v := r.Header.Get(HeaderCost); v != "" {
+				if cost, err = strconv.Atoi(v); err != nil || cost <= 0 {
+					writeMessage(w, http.StatusBadRequest, "invalid "+HeaderCost+" header")
+					return
+				}
+			}
+
+			decision, err = rl.DecidePolicy(r.Context(), policy, key, cost)
+		} else {
+			decision, err = rl.Decide(r.Context(), externalAddress(r, o.trusted), r.Header.Get("API_KEY"))
+		}
+
+		if errors.Is(err, limiter.ErrUnknownKind) {
+			writeMessage(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "ext_authz check failed", slog.Any("error", err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		middleware.SetLimitHeaders(w.Header(), decision)
+
+		if !decision.Allowed() {
+			middleware.WriteTooManyRequests(w)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// externalAddress usa o IP que o Envoy considera confiável para o cliente; a
+// conexão em si vem do próprio Envoy, por isso a porta do ext_authz só deve ser
+// acessível a ele
+func externalAddress(r *http.Request, trusted middleware.TrustedProxies) string {
+	if ip := r.Header.Get("X-Envoy-External-Address"); ip != "" {
+		return ip
+	}
+	return trusted.ClientIP(r)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(dto.ResponseMessage{Message: message})
+}
@@ -0,0 +1,199 @@
+package envoy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newTestLimiter(t *testing.T) *limiter.RateLimiter {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{
+		IpLimitRps:         2,
+		IpBlockDuration:    time.Minute,
+		TokenLimitRps:      5,
+		TokenBlockDuration: time.Minute,
+		KeyPrefix:          "test",
+	}
+	return limiterconfig.New(store, cfg,
+		limiter.WithPolicy(limiter.Policy{Name: "login", Limit: 3, Window: time.Minute, BlockDuration: 15 * time.Minute}))
+}
+
+func authz(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+	req.RemoteAddr = "10.0.0.1:50000"
+	for k, v := range header {
+		req.Header.Set(k, v[0])
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthzHandler_UsesExternalAddress(t *testing.T) {
+	h := NewAuthzHandler(newTestLimiter(t))
+	header := http.Header{"X-Envoy-External-Address": {"203.0.113.7"}}
+
+	for i := 0; i < 2; i++ {
+		if rec := authz(h, header); rec.Code != http.StatusOK {
+			t.Fatalf("check %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := authz(h, header)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	// outro cliente atrás do mesmo Envoy não é afetado
+	if rec := authz(h, http.Header{"X-Envoy-External-Address": {"203.0.113.8"}}); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for another client, got %d", rec.Code)
+	}
+}
+
+func TestAuthzHandler_NamedPolicy(t *testing.T) {
+	h := NewAuthzHandler(newTestLimiter(t), WithHeaderPolicies("login", "unknown"))
+	header := http.Header{HeaderPolicy: {"login"}, HeaderKey: {"alice"}, HeaderCost: {"2"}}
+
+	rec := authz(h, header)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("expected remaining 1, got %q", rec.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	if rec := authz(h, header); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", rec.Code)
+	}
+
+	invalid := []http.Header{
+		{HeaderPolicy: {"login"}},
+		{HeaderPolicy: {"login"}, HeaderKey: {"alice"}, HeaderCost: {"0"}},
+		{HeaderPolicy: {"unknown"}, HeaderKey: {"alice"}},
+	}
+	for _, header := range invalid {
+		if rec := authz(h, header); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", header, rec.Code)
+		}
+	}
+}
+
+func TestAuthzHandler_RejectsPoliciesOutsideTheAllowlist(t *testing.T) {
+	rl := newTestLimiter(t)
+
+	tests := []struct {
+		name   string
+		opts   []AuthzOption
+		policy string
+		want   int
+	}{
+		{"no allowlist", nil, "login", http.StatusForbidden},
+		{"not allowed", []AuthzOption{WithHeaderPolicies("login")}, "ip", http.StatusForbidden},
+		{"allowed", []AuthzOption{WithHeaderPolicies("login")}, "login", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAuthzHandler(rl, tt.opts...)
+			rec := authz(h, http.Header{HeaderPolicy: {tt.policy}, HeaderKey: {"bob"}})
+			if rec.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func descriptor(hits uint64, entries ...string) *ratelimitv3.RateLimitDescriptor {
+	d := &ratelimitv3.RateLimitDescriptor{}
+	for i := 0; i+1 < len(entries); i += 2 {
+		d.Entries = append(d.Entries, &ratelimitv3.RateLimitDescriptor_Entry{Key: entries[i], Value: entries[i+1]})
+	}
+	if hits > 0 {
+		d.HitsAddend = wrapperspb.UInt64(hits)
+	}
+	return d
+}
+
+func TestRateLimitService_ShouldRateLimit(t *testing.T) {
+	svc, err := NewRateLimitService(newTestLimiter(t), []string{"remote_address=ip", "user=login"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	req := &rlsv3.RateLimitRequest{
+		Domain: "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{
+			descriptor(0, "remote_address", "203.0.113.7"),
+			descriptor(2, "user", "alice", "path", "/login"),
+			descriptor(0, "generic_key", "unmapped"),
+		},
+	}
+
+	resp, err := svc.ShouldRateLimit(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("expected OK, got %v", resp.OverallCode)
+	}
+	if len(resp.Statuses) != 3 {
+		t.Fatalf("expected 3 statuses, got %d", len(resp.Statuses))
+	}
+
+	login := resp.Statuses[1]
+	if login.CurrentLimit.GetName() != "login" || login.CurrentLimit.GetUnit() != rlsv3.RateLimitResponse_RateLimit_MINUTE {
+		t.Errorf("unexpected current limit: %v", login.CurrentLimit)
+	}
+	if login.LimitRemaining != 1 {
+		t.Errorf("expected 1 remaining for login, got %d", login.LimitRemaining)
+	}
+	if resp.Statuses[2].CurrentLimit != nil {
+		t.Error("expected unmapped descriptor to have no limit")
+	}
+
+	resp, err = svc.ShouldRateLimit(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.OverallCode != rlsv3.RateLimitResponse_OVER_LIMIT {
+		t.Fatalf("expected OVER_LIMIT, got %v", resp.OverallCode)
+	}
+	if resp.Statuses[0].Code != rlsv3.RateLimitResponse_OK {
+		t.Errorf("expected ip descriptor to be OK, got %v", resp.Statuses[0].Code)
+	}
+	if got := resp.Statuses[1].DurationUntilReset.AsDuration(); got != 15*time.Minute {
+		t.Errorf("expected reset in 15m, got %v", got)
+	}
+}
+
+func TestNewRateLimitService_RejectsInvalidRules(t *testing.T) {
+	rl := newTestLimiter(t)
+
+	if _, err := NewRateLimitService(rl, []string{"remote_address"}); err == nil {
+		t.Error("expected error for rule without policy")
+	}
+	if _, err := NewRateLimitService(rl, []string{"remote_address=unknown"}); !errors.Is(err, limiter.ErrUnknownKind) {
+		t.Errorf("expected ErrUnknownKind, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RateLimitService implementa o Rate Limit Service do Envoy (ShouldRateLimit).
+// Cada descriptor é mapeado para uma política pela chave da sua primeira
+// entrada, e os valores das entradas formam o ID limitado.
+type RateLimitService struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+
+	rl    *limiter.RateLimiter
+	rules map[string]string
+}
+
+// NewRateLimitService cria o serviço com as regras "chave_do_descriptor=política"
+// (RLS_DESCRIPTORS). Uma chave sem regra que tenha o nome de uma política
+// registrada usa essa política.
+func NewRateLimitService(rl *limiter.RateLimiter, rules []string) (*RateLimitService, error) {
+	parsed, err := ParseDescriptorRules(rules, func(policy string) bool {
+		_, ok := rl.Policy(policy)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimitService{rl: rl, rules: parsed}, nil
+}
+
+// ParseDescriptorRules interpreta as regras "chave_do_descriptor=política";
+// known diz se uma política existe
+func ParseDescriptorRules(rules []string, known func(policy string) bool) (map[string]string, error) {
+	parsed := make(map[string]string, len(rules))
+
+	for _, rule := range rules {
+		key, policy, ok := strings.Cut(rule, "=")
+		if !ok || key == "" || policy == "" {
+			return nil, fmt.Errorf("invalid descriptor rule %q, expected descriptor_key=policy", rule)
+		}
+		if !known(policy) {
+			return nil, fmt.Errorf("descriptor rule %q: %w: %q", rule, limiter.ErrUnknownKind, policy)
+		}
+		parsed[key] = policy
+	}
+
+	return parsed, nil
+}
+
+// ShouldRateLimit decide cada descriptor separadamente; a requisição passa do
+// limite se qualquer um deles passar. Descriptors sem política são liberados.
+func (s *RateLimitService) ShouldRateLimit(ctx context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	resp := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+
+	for _, descriptor := range req.GetDescriptors() {
+		st, err := s.decide(ctx, descriptor, req.GetHitsAddend())
+		if err != nil {
+			slog.ErrorContext(ctx, "rate limit service check failed", slog.String("domain", req.GetDomain()), slog.Any("error", err))
+			return nil, status.Error(codes.Unavailable, "rate limiter unavailable")
+		}
+
+		if st.Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			resp.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+		}
+		resp.Statuses = append(resp.Statuses, st)
+	}
+
+	return resp, nil
+}
+
+func (s *RateLimitService) decide(ctx context.Context, descriptor *ratelimitv3.RateLimitDescriptor, hits uint32) (*rlsv3.RateLimitResponse_DescriptorStatus, error) {
+	entries := descriptor.GetEntries()
+	if len(entries) == 0 {
+		return &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}, nil
+	}
+
+	policy, ok := s.rules[entries[0].GetKey()]
+	if !ok {
+		policy = entries[0].GetKey()
+	}
+	p, ok := s.rl.Policy(policy)
+	if !ok {
+		return &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}, nil
+	}
+
+	values := make([]string, len(entries))
+	for i, e := range entries {
+		values[i] = e.GetValue()
+	}
+
+	// o hits_addend do descriptor tem precedência sobre o da requisição; zero vale 1
+	cost := uint64(hits)
+	if descriptor.GetHitsAddend() != nil {
+		cost = descriptor.GetHitsAddend().GetValue()
+	}
+	cost = min(max(cost, 1), math.MaxInt32)
+
+	d, err := s.rl.DecidePolicy(ctx, p.Name, strings.Join(values, "|"), int(cost))
+	if err != nil {
+		return nil, err
+	}
+
+	st := &rlsv3.RateLimitResponse_DescriptorStatus{
+		Code: rlsv3.RateLimitResponse_OK,
+		CurrentLimit: &rlsv3.RateLimitResponse_RateLimit{
+			Name:            p.Name,
+			RequestsPerUnit: uint32(p.Limit),
+			Unit:            unitOf(p.Window),
+		},
+		LimitRemaining: uint32(d.Remaining),
+	}
+	if !d.Allowed() {
+		st.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+	}
+	if d.Outcome == limiter.OutcomeDenied {
+		st.DurationUntilReset = durationpb.New(d.BlockDuration)
+	}
+
+	return st, nil
+}
+
+// unitOf converte a janela da política na unidade do Envoy; janelas que não
+// correspondem a uma unidade são informadas como UNKNOWN
+func unitOf(window time.Duration) rlsv3.RateLimitResponse_RateLimit_Unit {
+	switch window {
+	case time.Second:
+		return rlsv3.RateLimitResponse_RateLimit_SECOND
+	case time.Minute:
+		return rlsv3.RateLimitResponse_RateLimit_MINUTE
+	case time.Hour:
+		return rlsv3.RateLimitResponse_RateLimit_HOUR
+	case 24 * time.Hour:
+		return rlsv3.RateLimitResponse_RateLimit_DAY
+	default:
+		return rlsv3.RateLimitResponse_RateLimit_UNKNOWN
+	}
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"os"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewSinks monta os sinks listados em EVENT_SINKS; o sink redis usa a mesma
+// instância configurada para o storage
+func NewSinks(cfg *config.Config) []Sink {
+	var sinks []Sink
+
+	for _, name := range cfg.EventSinks {
+		switch name {
+		case "stdout":
+			sinks = append(sinks, NewWriterSink(os.Stdout))
+		case "webhook":
+			sinks = append(sinks, NewWebhookSink(cfg.EventWebhookURL,
+				WithWebhookSecret(cfg.EventWebhookSecret),
+				WithWebhookRetries(cfg.EventWebhookRetries),
+			))
+		case "redis":
+			client := redis.NewClient(&redis.Options{
+				Addr:     cfg.RedisAddr,
+				Password: cfg.RedisPassword,
+				DB:       cfg.RedisDB,
+			})
+			sinks = append(sinks, NewRedisStreamSink(client, cfg.EventRedisStream, cfg.EventRedisMaxLen))
+		}
+	}
+
+	return sinks
+}
@@ -0,0 +1,288 @@
+// Package events publica os bloqueios, desbloqueios e avisos de uso do limiter
+// para sinks externos (webhook, Redis stream, stdout), para que outras
+// ferramentas possam reagir a IPs e tokens abusivos.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/requestinfo"
+)
+
+type Type string
+
+const (
+	TypeBlocked          Type = "blocked"
+	TypeUnblocked        Type = "unblocked"
+	TypeThresholdWarning Type = "threshold_warning"
+)
+
+const (
+	SourceLimiter = "limiter"
+	SourceAdmin   = "admin"
+)
+
+// Event é o payload enviado aos sinks. O IP vai em claro para que o time de
+// segurança possa agir sobre ele; tokens só aparecem como hash.
+type Event struct {
+	ID           string     `json:"id"`
+	Type         Type       `json:"type"`
+	Time         time.Time  `json:"time"`
+	Source       string     `json:"source"`
+	KeyType      string     `json:"key_type"`
+	Key          string     `json:"key,omitempty"`
+	KeyHash      string     `json:"key_hash"`
+	Policy       string     `json:"policy,omitempty"`
+	Count        int64      `json:"count,omitempty"`
+	Limit        int        `json:"limit,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+	RequestID    string     `json:"request_id,omitempty"`
+	Route        string     `json:"route,omitempty"`
+}
+
+// Sink entrega eventos a um destino externo. Send é chamado por uma única
+// goroutine por sink, então implementações não precisam ser thread-safe.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, e Event) error
+}
+
+const (
+	DefaultThreshold  = 0.8
+	DefaultBufferSize = 1024
+)
+
+type busOptions struct {
+	threshold  float64
+	bufferSize int
+	now        func() time.Time
+	hasher     audit.Hasher
+}
+
+type BusOption func(*busOptions)
+
+// WithThreshold define a fração do limite que dispara threshold_warning; 0 desativa
+func WithThreshold(threshold float64) BusOption {
+	return func(o *busOptions) {
+		o.threshold = threshold
+	}
+}
+
+// WithBufferSize define quantos eventos cada sink pode acumular antes de descartar
+func WithBufferSize(size int) BusOption {
+	return func(o *busOptions) {
+		o.bufferSize = size
+	}
+}
+
+// WithKeyHasher define o hash de key_hash; deve ser o mesmo do audit log para
+// que eventos e registros de auditoria possam ser correlacionados
+func WithKeyHasher(hasher audit.Hasher) BusOption {
+	return func(o *busOptions) {
+		o.hasher = hasher
+	}
+}
+
+func WithNow(now func() time.Time) BusOption {
+	return func(o *busOptions) {
+		o.now = now
+	}
+}
+
+// Bus entrega os eventos a cada sink de forma assíncrona, com uma fila por sink
+// para que um webhook lento não atrase os demais nem as requisições
+type Bus struct {
+	workers   []*worker
+	threshold float64
+	now       func() time.Time
+	hasher    audit.Hasher
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type worker struct {
+	sink  Sink
+	queue chan Event
+	done  chan struct{}
+}
+
+func NewBus(sinks []Sink, opts ...BusOption) *Bus {
+	o := busOptions{
+		threshold:  DefaultThreshold,
+		bufferSize: DefaultBufferSize,
+		now:        time.Now,
+		hasher:     audit.NewRandomHasher(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	b := &Bus{threshold: o.threshold, now: o.now, hasher: o.hasher}
+	for _, sink := range sinks {
+		w := &worker{
+			sink:  sink,
+			queue: make(chan Event, o.bufferSize),
+			done:  make(chan struct{}),
+		}
+		b.workers = append(b.workers, w)
+		go w.run()
+	}
+
+	return b
+}
+
+func (w *worker) run() {
+	defer close(w.done)
+
+	for e := range w.queue {
+		if err := w.sink.Send(context.Background(), e); err != nil {
+			slog.Error("failed to deliver event",
+				slog.String("sink", w.sink.Name()),
+				slog.String("event_id", e.ID),
+				slog.String("event_type", string(e.Type)),
+				slog.Any("error", err))
+		}
+	}
+}
+
+// Publish enfileira o evento em todos os sinks sem bloquear; com a fila cheia
+// o evento é descartado para aquele sink
+func (b *Bus) Publish(e Event) {
+	if e.ID == "" {
+		e.ID = newEventID()
+	}
+	if e.Time.IsZero() {
+		e.Time = b.now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, w := range b.workers {
+		select {
+		case w.queue <- e:
+		default:
+			slog.Warn("event queue full, dropping event",
+				slog.String("sink", w.sink.Name()),
+				slog.String("event_type", string(e.Type)))
+		}
+	}
+}
+
+// Close para de aceitar eventos, espera os sinks esvaziarem as filas (ou o ctx
+// expirar) e fecha os sinks que implementam io.Closer
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, w := range b.workers {
+			close(w.queue)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, w := range b.workers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var errs []error
+	for _, w := range b.workers {
+		if c, ok := w.sink.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Bus) ObserveDecision(ctx context.Context, d limiter.Decision) {
+	switch {
+	case d.Outcome == limiter.OutcomeDenied:
+		e := b.newEvent(ctx, TypeBlocked, SourceLimiter, d.Kind, d.ID)
+		e.Policy = d.Policy
+		e.Count = d.Count
+		e.Limit = d.Limit
+		until := d.BlockedUntil
+		e.BlockedUntil = &until
+		b.Publish(e)
+	case d.Outcome == limiter.OutcomeAllowed && b.crossesThreshold(d):
+		// só na requisição que atinge o limiar, para não gerar um evento por requisição
+		e := b.newEvent(ctx, TypeThresholdWarning, SourceLimiter, d.Kind, d.ID)
+		e.Policy = d.Policy
+		e.Count = d.Count
+		e.Limit = d.Limit
+		b.Publish(e)
+	}
+}
+
+func (b *Bus) ObserveBlock(ctx context.Context, kind, id string, until time.Time) {
+	e := b.newEvent(ctx, TypeBlocked, SourceAdmin, kind, id)
+	e.BlockedUntil = &until
+	b.Publish(e)
+}
+
+// ObserveUnblock publica unblocked para desbloqueios e resets manuais. Bloqueios
+// que expiram não geram evento; consumidores usam o BlockedUntil de blocked.
+func (b *Bus) ObserveUnblock(ctx context.Context, kind, id string) {
+	b.Publish(b.newEvent(ctx, TypeUnblocked, SourceAdmin, kind, id))
+}
+
+// crossesThreshold diz se esta decisão levou o contador do limiar para cima;
+// com custo maior que 1 a contagem pode pular o valor exato do limiar
+func (b *Bus) crossesThreshold(d limiter.Decision) bool {
+	warnAt := b.warnAt(d.Limit)
+	return warnAt > 0 && d.Count >= warnAt && d.Count-int64(d.Cost) < warnAt
+}
+
+// warnAt retorna a contagem que dispara threshold_warning, ou -1 se desativado
+func (b *Bus) warnAt(limit int) int64 {
+	if b.threshold <= 0 || b.threshold >= 1 || limit <= 0 {
+		return -1
+	}
+	return int64(math.Ceil(b.threshold * float64(limit)))
+}
+
+func (b *Bus) newEvent(ctx context.Context, t Type, source, kind, id string) Event {
+	e := Event{
+		ID:      newEventID(),
+		Type:    t,
+		Time:    b.now(),
+		Source:  source,
+		KeyType: kind,
+		KeyHash: b.hasher.HashKey(id),
+	}
+	if kind == limiter.KindIP {
+		// nas políticas de rota o id tem o prefixo de limiter.ClientID
+		e.Key = strings.TrimPrefix(id, limiter.KindIP+":")
+	}
+	if info, ok := requestinfo.FromContext(ctx); ok {
+		e.RequestID = info.RequestID
+		e.Route = info.Route
+	}
+	return e
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
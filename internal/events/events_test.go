@@ -0,0 +1,199 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/audit"
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/events"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(_ context.Context, e events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+var testHasher = audit.NewHasher([]byte("test-secret"))
+
+func setupLimiter(t *testing.T, sink events.Sink) (*limiter.RateLimiter, *events.Bus) {
+	t.Helper()
+
+	cfg := &config.Config{IpLimitRps: 5, IpBlockDuration: time.Minute, TokenLimitRps: 5, TokenBlockDuration: time.Minute}
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	bus := events.NewBus([]events.Sink{sink}, events.WithKeyHasher(testHasher))
+	return limiterconfig.New(store, cfg, limiter.WithObserver(bus)), bus
+}
+
+func TestBus_EmitsLimiterEvents(t *testing.T) {
+	sink := &recordingSink{}
+	rl, bus := setupLimiter(t, sink)
+	ctx := context.Background()
+
+	for i := 0; i < 8; i++ {
+		rl.Allow(ctx, "1.2.3.4", "")
+		rl.Allow(ctx, "5.6.7.8", "secret-token")
+	}
+	if err := rl.Unblock(ctx, limiter.KindIP, "1.2.3.4"); err != nil {
+		t.Fatalf("unblock: %v", err)
+	}
+	// desbloquear uma chave que não está bloqueada não gera evento
+	if err := rl.Unblock(ctx, limiter.KindIP, "9.9.9.9"); err != nil {
+		t.Fatalf("unblock: %v", err)
+	}
+
+	if err := bus.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	want := []struct {
+		typ     events.Type
+		keyType string
+		count   int64
+	}{
+		{events.TypeThresholdWarning, limiter.KindIP, 4},
+		{events.TypeThresholdWarning, limiter.KindToken, 4},
+		{events.TypeBlocked, limiter.KindIP, 6},
+		{events.TypeBlocked, limiter.KindToken, 6},
+		{events.TypeUnblocked, limiter.KindIP, 0},
+	}
+	if len(sink.events) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(sink.events), sink.events)
+	}
+	for i, w := range want {
+		e := sink.events[i]
+		if e.Type != w.typ || e.KeyType != w.keyType || e.Count != w.count {
+			t.Errorf("event %d: expected %s/%s/%d, got %s/%s/%d", i, w.typ, w.keyType, w.count, e.Type, e.KeyType, e.Count)
+		}
+		if e.ID == "" || e.Time.IsZero() {
+			t.Errorf("event %d: missing id or time", i)
+		}
+	}
+
+	ipBlocked, tokenBlocked := sink.events[2], sink.events[3]
+	if ipBlocked.Key != "1.2.3.4" || ipBlocked.BlockedUntil == nil || ipBlocked.Source != events.SourceLimiter {
+		t.Errorf("unexpected ip blocked event: %+v", ipBlocked)
+	}
+	if tokenBlocked.Key != "" || tokenBlocked.KeyHash != testHasher.HashKey("secret-token") {
+		t.Errorf("token event must only carry the hash: %+v", tokenBlocked)
+	}
+	if unblocked := sink.events[4]; unblocked.Source != events.SourceAdmin {
+		t.Errorf("expected unblocked event from admin, got %q", unblocked.Source)
+	}
+}
+
+func TestBus_ThresholdWarningWithCost(t *testing.T) {
+	sink := &recordingSink{}
+	rl, bus := setupLimiter(t, sink)
+	ctx := context.Background()
+
+	// o limiar é 4 de 5; com custo 3 e depois 2 a contagem pula de 3 para 5
+	for _, cost := range []int{3, 2} {
+		if _, err := rl.DecidePolicy(ctx, limiter.KindIP, "1.2.3.4", cost); err != nil {
+			t.Fatalf("decide: %v", err)
+		}
+	}
+	bus.Close(ctx)
+
+	if len(sink.events) != 1 || sink.events[0].Type != events.TypeThresholdWarning || sink.events[0].Count != 5 {
+		t.Fatalf("expected one threshold warning at count 5, got %+v", sink.events)
+	}
+}
+
+func TestBus_ManualBlock(t *testing.T) {
+	sink := &recordingSink{}
+	rl, bus := setupLimiter(t, sink)
+	ctx := context.Background()
+
+	if err := rl.Block(ctx, limiter.KindIP, "1.2.3.4", time.Minute); err != nil {
+		t.Fatalf("block: %v", err)
+	}
+	if err := rl.Reset(ctx, limiter.KindIP, "1.2.3.4"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	bus.Close(ctx)
+
+	if len(sink.events) != 2 || sink.events[0].Type != events.TypeBlocked || sink.events[1].Type != events.TypeUnblocked {
+		t.Fatalf("expected blocked and unblocked events, got %+v", sink.events)
+	}
+}
+
+func TestWebhookSink_RetriesAndSigns(t *testing.T) {
+	var attempts atomic.Int32
+	var received events.Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		want := events.Sign("s3cret", r.Header.Get(events.TimestampHeader), body)
+		if got := r.Header.Get(events.SignatureHeader); got != want {
+			t.Errorf("expected signature %q, got %q", want, got)
+		}
+		if got := r.Header.Get(events.EventTypeHeader); got != string(events.TypeBlocked) {
+			t.Errorf("expected event type header %q, got %q", events.TypeBlocked, got)
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := events.NewWebhookSink(server.URL,
+		events.WithWebhookSecret("s3cret"),
+		events.WithWebhookRetries(3),
+		events.WithWebhookBackoff(time.Millisecond),
+	)
+
+	err := sink.Send(context.Background(), events.Event{ID: "evt-1", Type: events.TypeBlocked, KeyType: limiter.KindIP, Key: "1.2.3.4"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts.Load())
+	}
+	if received.ID != "evt-1" || received.Key != "1.2.3.4" {
+		t.Errorf("unexpected payload: %+v", received)
+	}
+}
+
+func TestWebhookSink_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := events.NewWebhookSink(server.URL, events.WithWebhookBackoff(time.Millisecond))
+	if err := sink.Send(context.Background(), events.Event{Type: events.TypeBlocked}); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts.Load())
+	}
+}
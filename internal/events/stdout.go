@@ -0,0 +1,24 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// WriterSink escreve cada evento como uma linha JSON
+type WriterSink struct {
+	enc *json.Encoder
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{enc: json.NewEncoder(w)}
+}
+
+func (s *WriterSink) Name() string {
+	return "stdout"
+}
+
+func (s *WriterSink) Send(_ context.Context, e Event) error {
+	return s.enc.Encode(e)
+}
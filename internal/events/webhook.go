@@ -0,0 +1,147 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Ratelimiter-Signature"
+	TimestampHeader = "X-Ratelimiter-Timestamp"
+	EventTypeHeader = "X-Ratelimiter-Event"
+)
+
+type webhookOptions struct {
+	secret  string
+	retries int
+	backoff time.Duration
+	client  *http.Client
+}
+
+type WebhookOption func(*webhookOptions)
+
+// WithWebhookSecret ativa a assinatura HMAC-SHA256 dos payloads
+func WithWebhookSecret(secret string) WebhookOption {
+	return func(o *webhookOptions) {
+		o.secret = secret
+	}
+}
+
+// WithWebhookRetries define quantas vezes a entrega é repetida após a primeira falha
+func WithWebhookRetries(retries int) WebhookOption {
+	return func(o *webhookOptions) {
+		o.retries = retries
+	}
+}
+
+// WithWebhookBackoff define a espera antes da primeira repetição; dobra a cada tentativa
+func WithWebhookBackoff(backoff time.Duration) WebhookOption {
+	return func(o *webhookOptions) {
+		o.backoff = backoff
+	}
+}
+
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(o *webhookOptions) {
+		o.client = client
+	}
+}
+
+// WebhookSink faz POST de cada evento em JSON. Com secret, o header
+// X-Ratelimiter-Signature leva "sha256=" + HMAC de "<timestamp>.<corpo>", onde
+// timestamp é o valor de X-Ratelimiter-Timestamp (segundos Unix).
+type WebhookSink struct {
+	url  string
+	opts webhookOptions
+}
+
+func NewWebhookSink(url string, opts ...WebhookOption) *WebhookSink {
+	o := webhookOptions{
+		retries: 3,
+		backoff: 500 * time.Millisecond,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &WebhookSink{url: url, opts: o}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Send(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	backoff := s.opts.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, e.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.opts.retries {
+			return fmt.Errorf("webhook delivery failed after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// post retorna se vale a pena tentar de novo: erros de rede, 429 e 5xx
+func (s *WebhookSink) post(ctx context.Context, t Type, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, string(t))
+	req.Header.Set(TimestampHeader, timestamp)
+	if s.opts.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.opts.secret, timestamp, body))
+	}
+
+	resp, err := s.opts.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// Sign calcula a assinatura enviada em X-Ratelimiter-Signature; os receptores
+// podem usá-la para validar o payload
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
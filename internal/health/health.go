@@ -0,0 +1,140 @@
+// Package health implementa as rotas de liveness e readiness. A liveness só
+// indica que o processo responde; a readiness verifica as dependências (storage)
+// e sua latência, para que o balanceador tire a instância de rotação quando
+// elas falham.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/dto"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+	// StatusDraining é a readiness durante o encerramento
+	StatusDraining = "draining"
+)
+
+// Check é uma dependência verificada pela readiness. Falhas de checks não
+// críticos deixam a instância "degraded", mas ainda pronta.
+type Check struct {
+	Name       string
+	Check      func(ctx context.Context) error
+	MaxLatency time.Duration
+	Critical   bool
+}
+
+type Checker struct {
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// New cria o checker; timeout limita o tempo de cada verificação
+func New(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Live responde sempre 200 enquanto o processo consegue atender requisições
+func (c *Checker) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, dto.ResponseHealth{Status: StatusOK})
+}
+
+// Drain faz a readiness falhar a partir de agora, para que o balanceador tire
+// a instância de rotação enquanto ela encerra
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready executa os checks em paralelo e responde 503 se algum crítico falhar
+// ou se a instância estiver encerrando
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	if c.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, dto.ResponseReadiness{Status: StatusDraining})
+		return
+	}
+
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// Run executa os checks e monta o relatório por dependência
+func (c *Checker) Run(ctx context.Context) dto.ResponseReadiness {
+	results := make([]dto.DependencyHealth, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := dto.ResponseReadiness{
+		Status: StatusOK,
+		Checks: make(map[string]dto.DependencyHealth, len(c.checks)),
+	}
+	for i, check := range c.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+
+		if result.Status == StatusOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) dto.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	latency := time.Since(start)
+
+	result := dto.DependencyHealth{
+		Status:    StatusOK,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+
+	switch {
+	case err != nil:
+		result.Status = StatusFail
+		result.Error = err.Error()
+	case check.MaxLatency > 0 && latency > check.MaxLatency:
+		result.Status = StatusFail
+		result.Error = fmt.Sprintf("latency %s above threshold %s", latency.Round(time.Millisecond), check.MaxLatency)
+	}
+
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
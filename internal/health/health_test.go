@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errors.New("connection refused") }
+
+func slow(ctx context.Context) error {
+	time.Sleep(20 * time.Millisecond)
+	return nil
+}
+
+func TestChecker_Ready(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus int
+		wantReport string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "all ok",
+			checks:     []Check{{Name: "storage", Check: ok, Critical: true}},
+			wantStatus: http.StatusOK,
+			wantReport: StatusOK,
+			wantChecks: map[string]string{"storage": StatusOK},
+		},
+		{
+			name:       "critical failure",
+			checks:     []Check{{Name: "storage", Check: failing, Critical: true}, {Name: "other", Check: ok}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: StatusFail,
+			wantChecks: map[string]string{"storage": StatusFail, "other": StatusOK},
+		},
+		{
+			name:       "non critical failure",
+			checks:     []Check{{Name: "storage", Check: failing}},
+			wantStatus: http.StatusOK,
+			wantReport: StatusDegraded,
+			wantChecks: map[string]string{"storage": StatusFail},
+		},
+		{
+			name:       "latency above threshold",
+			checks:     []Check{{Name: "storage", Check: slow, MaxLatency: time.Millisecond, Critical: true}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantReport: StatusFail,
+			wantChecks: map[string]string{"storage": StatusFail},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			New(time.Second, tt.checks...).Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			var report dto.ResponseReadiness
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if report.Status != tt.wantReport {
+				t.Errorf("expected report status %q, got %q", tt.wantReport, report.Status)
+			}
+			for name, want := range tt.wantChecks {
+				got := report.Checks[name]
+				if got.Status != want {
+					t.Errorf("check %s: expected %q, got %q", name, want, got.Status)
+				}
+				if want == StatusFail && got.Error == "" {
+					t.Errorf("check %s: expected error message", name)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_ReadyReflectsStorage(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	checker := New(time.Second, Check{Name: "storage", Check: store.Ping, Critical: true})
+
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		checker.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if status := ready(); status != http.StatusOK {
+		t.Fatalf("expected ready with open storage, got %d", status)
+	}
+
+	store.Close()
+	if status := ready(); status != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready after storage closed, got %d", status)
+	}
+
+	// a liveness não depende do storage
+	rec := httptest.NewRecorder()
+	checker.Live(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected live, got %d", rec.Code)
+	}
+}
+
+func TestChecker_ReadyFailsWhileDraining(t *testing.T) {
+	checker := New(time.Second, Check{Name: "storage", Check: ok, Critical: true})
+	checker.Drain()
+
+	rec := httptest.NewRecorder()
+	checker.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected not ready while draining, got %d", rec.Code)
+	}
+
+	var report dto.ResponseReadiness
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if report.Status != StatusDraining {
+		t.Errorf("expected status %q, got %q", StatusDraining, report.Status)
+	}
+
+	// a liveness continua ok até o processo sair
+	rec = httptest.NewRecorder()
+	checker.Live(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected live while draining, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+type Outcome string
+
+const (
+	OutcomeAllowed Outcome = "allowed"
+	// OutcomeDenied indica que o limite foi excedido nesta requisição e a chave foi bloqueada
+	OutcomeDenied Outcome = "denied"
+	// OutcomeBlocked indica que a chave já estava bloqueada
+	OutcomeBlocked Outcome = "blocked"
+	// OutcomeFailOpen indica que o storage falhou e a requisição foi liberada (WithFailOpen)
+	OutcomeFailOpen Outcome = "fail_open"
+)
+
+// Decision descreve o resultado de uma verificação de rate limit
+type Decision struct {
+	// Kind é o tipo da chave (ip ou token); o contador é o de Policy e ID
+	Kind    string
+	ID      string
+	Policy  string
+	Outcome Outcome
+	// Cost é quanto esta requisição consome do limite (1 no middleware)
+	Cost int
+	// Count é o valor do contador após esta requisição (zero quando bloqueado)
+	Count         int64
+	Limit         int
+	Remaining     int
+	Window        time.Duration
+	BlockDuration time.Duration
+	// BlockedUntil só é preenchido quando a chave foi bloqueada nesta requisição
+	BlockedUntil time.Time
+}
+
+func (d Decision) Allowed() bool {
+	return d.Outcome == OutcomeAllowed || d.Outcome == OutcomeFailOpen
+}
+
+// Observer é notificado de cada decisão do limiter (métricas, logs, eventos...).
+// É chamado de forma síncrona, então implementações não devem bloquear.
+type Observer interface {
+	ObserveDecision(ctx context.Context, d Decision)
+}
+
+// StateObserver pode ser implementado por um Observer que também queira saber
+// dos bloqueios e desbloqueios feitos fora do fluxo de requisições (API de
+// administração, CLI)
+type StateObserver interface {
+	ObserveBlock(ctx context.Context, kind, id string, until time.Time)
+	ObserveUnblock(ctx context.Context, kind, id string)
+}
+
+// WithObserver registra um observer; pode ser usado mais de uma vez
+func WithObserver(o Observer) Option {
+	return func(rl *RateLimiter) {
+		rl.observers = append(rl.observers, o)
+	}
+}
+
+// WithFailOpen libera as requisições quando o storage falha, em vez de retornar erro
+func WithFailOpen(enabled bool) Option {
+	return func(rl *RateLimiter) {
+		rl.failOpen = enabled
+	}
+}
+
+func (rl *RateLimiter) notify(ctx context.Context, d Decision) {
+	for _, o := range rl.observers {
+		o.ObserveDecision(ctx, d)
+	}
+}
+
+func (rl *RateLimiter) notifyBlock(ctx context.Context, kind, id string, until time.Time) {
+	for _, o := range rl.observers {
+		if so, ok := o.(StateObserver); ok {
+			so.ObserveBlock(ctx, kind, id, until)
+		}
+	}
+}
+
+func (rl *RateLimiter) notifyUnblock(ctx context.Context, kind, id string) {
+	for _, o := range rl.observers {
+		if so, ok := o.(StateObserver); ok {
+			so.ObserveUnblock(ctx, kind, id)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package limiter
+
+import "strings"
+
+// KeySchemaVersion identifica o layout das chaves e valores gravados no storage.
+// Deve ser incrementado sempre que o algoritmo mudar de forma incompatível, para
+// que contadores antigos sejam ignorados em vez de reinterpretados.
+//
+// v2: no Redis o contador passou de string para hash (count, start, end).
+// v3: ":" e "%" do id são escapados (idEscaper).
+const KeySchemaVersion = "v3"
+
+// idEscaper escapa o id para que ele nunca produza um ":" na chave. Sem isso o
+// token "X:blocked" teria a mesma chave que o marcador de bloqueio do token X
+// no Redis.
+var (
+	idEscaper   = strings.NewReplacer("%", "%25", ":", "%3A")
+	idUnescaper = strings.NewReplacer("%25", "%", "%3A", ":")
+)
+
+// KeyBuilder monta as chaves no formato <prefix>:<namespace>:<versão>:<tipo>:<id>,
+// com o id escapado
+type KeyBuilder struct {
+	base string
+}
+
+func NewKeyBuilder(prefix, namespace string) KeyBuilder {
+	parts := make([]string, 0, 3)
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	if namespace != "" {
+		parts = append(parts, namespace)
+	}
+	parts = append(parts, KeySchemaVersion)
+
+	return KeyBuilder{base: strings.Join(parts, ":")}
+}
+
+func (b KeyBuilder) Key(kind, id string) string {
+	return b.base + ":" + kind + ":" + idEscaper.Replace(id)
+}
+
+// Parse extrai o tipo e o id de uma chave gerada por este builder
+func (b KeyBuilder) Parse(key string) (kind, id string, ok bool) {
+	rest, found := strings.CutPrefix(key, b.Prefix())
+	if !found {
+		return "", "", false
+	}
+
+	kind, id, found = strings.Cut(rest, ":")
+	if !found || kind == "" || id == "" || strings.Contains(id, ":") {
+		return "", "", false
+	}
+
+	return kind, idUnescaper.Replace(id), true
+}
+
+// Prefix retorna o prefixo comum a todas as chaves geradas por este builder
+func (b KeyBuilder) Prefix() string {
+	return b.base + ":"
+}
@@ -0,0 +1,67 @@
+package limiter
+
+import "testing"
+
+func TestKeyBuilder_Key(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		namespace string
+		want      string
+	}{
+		{name: "prefix and namespace", prefix: "ratelimiter", namespace: "prod-api", want: "ratelimiter:prod-api:" + KeySchemaVersion + ":ip:1.2.3.4"},
+		{name: "prefix only", prefix: "ratelimiter", want: "ratelimiter:" + KeySchemaVersion + ":ip:1.2.3.4"},
+		{name: "no prefix", want: KeySchemaVersion + ":ip:1.2.3.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewKeyBuilder(tt.prefix, tt.namespace).Key("ip", "1.2.3.4")
+			if got != tt.want {
+				t.Errorf("expected key %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestKeyBuilder_NamespacesDoNotCollide(t *testing.T) {
+	a := NewKeyBuilder("ratelimiter", "service-a").Key("token", "abc")
+	b := NewKeyBuilder("ratelimiter", "service-b").Key("token", "abc")
+
+	if a == b {
+		t.Errorf("expected different keys for different namespaces, got %q for both", a)
+	}
+}
+
+func TestKeyBuilder_Parse(t *testing.T) {
+	b := NewKeyBuilder("ratelimiter", "prod")
+
+	kind, id, ok := b.Parse(b.Key("ip", "::1"))
+	if !ok || kind != "ip" || id != "::1" {
+		t.Errorf("expected (ip, ::1, true), got (%s, %s, %v)", kind, id, ok)
+	}
+
+	if _, _, ok := b.Parse(NewKeyBuilder("ratelimiter", "staging").Key("ip", "::1")); ok {
+		t.Error("expected key from another namespace not to be parsed")
+	}
+}
+
+func TestKeyBuilder_EscapesID(t *testing.T) {
+	b := NewKeyBuilder("ratelimiter", "")
+
+	// o marcador de bloqueio do Redis é a chave com o sufixo ":blocked"
+	if a, marker := b.Key("token", "X:blocked"), b.Key("token", "X")+":blocked"; a == marker {
+		t.Errorf("expected token %q not to collide with the block marker, got %q", "X:blocked", a)
+	}
+
+	for _, id := range []string{"X:blocked", "100%", "%3A", "token:abc"} {
+		kind, got, ok := b.Parse(b.Key("orders", id))
+		if !ok || kind != "orders" || got != id {
+			t.Errorf("expected (orders, %s, true), got (%s, %s, %v)", id, kind, got, ok)
+		}
+	}
+
+	if _, _, ok := b.Parse(b.Key("token", "X") + ":blocked"); ok {
+		t.Error("expected a block marker not to be parsed as a key")
+	}
+}
@@ -2,72 +2,356 @@ package limiter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/clock"
 	"github.com/alexduzi/labratelimiter/internal/storage"
 )
 
 type RateLimiter struct {
 	storage storage.Storage
-	cfg     *config.Config
+	keys    KeyBuilder
+	clock   clock.Clock
+
+	policies map[string]Policy
+
+	observers []Observer
+	failOpen  bool
+}
+
+type Option func(*RateLimiter)
+
+// WithClock substitui o relógio do limiter. Para testes determinísticos o mesmo
+// relógio deve ser passado ao storage (ex.: storage.WithMemoryClock).
+func WithClock(c clock.Clock) Option {
+	return func(rl *RateLimiter) {
+		rl.clock = c
+	}
 }
 
-func NewRateLimiter(storage storage.Storage, cfg *config.Config) *RateLimiter {
-	return &RateLimiter{
+// New cria o limiter sem depender da configuração do servidor. Sem opções, usa
+// os mesmos padrões das variáveis de ambiente: 10 req/s por IP, 100 req/s por
+// token, bloqueio de 5 minutos e prefixo "ratelimiter".
+func New(storage storage.Storage, opts ...Option) *RateLimiter {
+	rl := &RateLimiter{
 		storage: storage,
-		cfg:     cfg,
+		keys:    NewKeyBuilder("ratelimiter", ""),
+		clock:   clock.Real(),
+		policies: map[string]Policy{
+			KindIP:    {Name: KindIP, Limit: 10, Window: time.Second, BlockDuration: 5 * time.Minute},
+			KindToken: {Name: KindToken, Limit: 100, Window: time.Second, BlockDuration: 5 * time.Minute},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(rl)
 	}
+
+	return rl
 }
 
-func (rl *RateLimiter) AllowIP(ctx context.Context, ip string) (bool, error) {
-	key := fmt.Sprintf("ip:%s", ip)
+// WithIPLimit define o limite por segundo e o bloqueio da política "ip"
+func WithIPLimit(limit int, blockDuration time.Duration) Option {
+	return WithPolicy(Policy{Name: KindIP, Limit: limit, Window: time.Second, BlockDuration: blockDuration})
+}
 
-	return rl.allow(ctx, key, rl.cfg.IpLimitRps, rl.cfg.IpBlockDuration)
+// WithTokenLimit define o limite por segundo e o bloqueio da política "token"
+func WithTokenLimit(limit int, blockDuration time.Duration) Option {
+	return WithPolicy(Policy{Name: KindToken, Limit: limit, Window: time.Second, BlockDuration: blockDuration})
+}
+
+// WithKeyPrefix define o prefixo e o namespace das chaves no storage, para
+// separar aplicações ou ambientes que compartilham o mesmo Redis
+func WithKeyPrefix(prefix, namespace string) Option {
+	return func(rl *RateLimiter) {
+		rl.keys = NewKeyBuilder(prefix, namespace)
+	}
+}
+
+// TracerName identifica os spans criados por este projeto
+const TracerName = "github.com/alexduzi/labratelimiter"
+
+const (
+	KindIP    = "ip"
+	KindToken = "token"
+)
+
+// ClientID é o id do cliente nas políticas customizadas: o token ou, sem token,
+// o IP. Os prefixos separam os dois espaços de chaves, senão o token "1.2.3.4"
+// dividiria o limite com o IP 1.2.3.4.
+func ClientID(token, ip string) string {
+	if token != "" {
+		return KindToken + ":" + token
+	}
+	return KindIP + ":" + ip
+}
+
+// keyKind retorna o tipo da chave (ip ou token) de uma decisão. Nas políticas
+// customizadas vem do prefixo de ClientID; ids montados de outra forma (RLS,
+// ext_authz, API de verificação) ficam com o nome da política.
+func keyKind(policy, id string) string {
+	if policy == KindIP || policy == KindToken {
+		return policy
+	}
+	if kind, _, ok := strings.Cut(id, ":"); ok && (kind == KindIP || kind == KindToken) {
+		return kind
+	}
+	return policy
+}
+
+// ErrUnknownKind indica um tipo de chave que não corresponde a nenhuma política
+var ErrUnknownKind = errors.New("unknown key kind")
+
+func (rl *RateLimiter) AllowIP(ctx context.Context, ip string) (bool, error) {
+	d, err := rl.DecideIP(ctx, ip)
+	return d.Allowed(), err
 }
 
 func (rl *RateLimiter) AllowToken(ctx context.Context, token string) (bool, error) {
-	key := fmt.Sprintf("token:%s", token)
+	d, err := rl.DecideToken(ctx, token)
+	return d.Allowed(), err
+}
+
+// Allow verifica IP ou Token (token tem precedência)
+func (rl *RateLimiter) Allow(ctx context.Context, ip, token string) (bool, error) {
+	d, err := rl.Decide(ctx, ip, token)
+	return d.Allowed(), err
+}
+
+func (rl *RateLimiter) DecideIP(ctx context.Context, ip string) (Decision, error) {
+	return rl.DecidePolicy(ctx, KindIP, ip, 1)
+}
+
+func (rl *RateLimiter) DecideToken(ctx context.Context, token string) (Decision, error) {
+	return rl.DecidePolicy(ctx, KindToken, token, 1)
+}
+
+// DecidePolicy consome cost unidades da chave id na política informada. O
+// contador de cada política é separado; "ip" e "token" são as usadas pelo
+// middleware.
+func (rl *RateLimiter) DecidePolicy(ctx context.Context, policy, id string, cost int) (Decision, error) {
+	d, err := rl.partial(policy, id, cost)
+	if err != nil {
+		return d, err
+	}
+
+	return rl.decide(ctx, d)
+}
 
-	return rl.allow(ctx, key, rl.cfg.TokenLimitRps, rl.cfg.TokenBlockDuration)
+// Peek retorna a decisão que DecidePolicy tomaria agora, sem consumir o limite,
+// bloquear a chave nem notificar os observers (dry-run)
+func (rl *RateLimiter) Peek(ctx context.Context, policy, id string, cost int) (Decision, error) {
+	d, err := rl.partial(policy, id, cost)
+	if err != nil {
+		return d, err
+	}
+
+	state, err := rl.storage.GetState(ctx, rl.keys.Key(d.Policy, d.ID))
+	if err != nil {
+		if !rl.failOpen {
+			return d, fmt.Errorf("failed to get state: %w", err)
+		}
+		d.Outcome = OutcomeFailOpen
+		return d, nil
+	}
+
+	if state.Blocked() {
+		d.Outcome = OutcomeBlocked
+		return d, nil
+	}
+
+	d.Count = state.Count + int64(d.Cost)
+	d.Remaining = max(d.Limit-int(d.Count), 0)
+	if int(d.Count) > d.Limit {
+		d.Outcome = OutcomeDenied
+		d.BlockedUntil = rl.clock.Now().Add(d.BlockDuration)
+		return d, nil
+	}
+
+	d.Outcome = OutcomeAllowed
+	return d, nil
+}
+
+// partial monta a decisão com os dados da política, ainda sem resultado
+func (rl *RateLimiter) partial(policy, id string, cost int) (Decision, error) {
+	p, ok := rl.policies[policy]
+	if !ok {
+		return Decision{}, fmt.Errorf("%w: %q", ErrUnknownKind, policy)
+	}
+	if cost <= 0 {
+		return Decision{}, ErrInvalidCost
+	}
+
+	return Decision{
+		Kind:          keyKind(p.Name, id),
+		ID:            id,
+		Policy:        p.Name,
+		Cost:          cost,
+		Limit:         p.Limit,
+		Window:        p.Window,
+		BlockDuration: p.BlockDuration,
+	}, nil
+}
+
+// Decide é como Allow, mas retorna os detalhes da decisão
+func (rl *RateLimiter) Decide(ctx context.Context, ip, token string) (Decision, error) {
+	if token != "" {
+		return rl.DecideToken(ctx, token)
+	}
+
+	return rl.DecideIP(ctx, ip)
+}
+
+// decide aplica allow à decisão parcial (tipo, id e política) e notifica os observers
+func (rl *RateLimiter) decide(ctx context.Context, d Decision) (Decision, error) {
+	key := rl.keys.Key(d.Policy, d.ID)
+
+	if err := rl.allow(ctx, key, &d); err != nil {
+		if !rl.failOpen {
+			return d, err
+		}
+		d.Outcome = OutcomeFailOpen
+	}
+
+	rl.notify(ctx, d)
+	return d, nil
 }
 
 // allow é a lógica central do rate limiting
-func (rl *RateLimiter) allow(ctx context.Context, key string, limit int, blockDuration time.Duration) (bool, error) {
+func (rl *RateLimiter) allow(ctx context.Context, key string, d *Decision) error {
 	// 1. Verifica se está bloqueado
 	blocked, err := rl.storage.IsBlocked(ctx, key)
 	if err != nil {
-		return false, fmt.Errorf("failed to check if blocked: %w", err)
+		return fmt.Errorf("failed to check if blocked: %w", err)
 	}
 
 	if blocked {
-		return false, nil
+		d.Outcome = OutcomeBlocked
+		return nil
 	}
 
 	// 2. Incrementa o contador
-	count, err := rl.storage.Increment(ctx, key, time.Second)
+	count, err := rl.storage.IncrementBy(ctx, key, int64(d.Cost), d.Window)
 	if err != nil {
-		return false, fmt.Errorf("failed to increment counter: %w", err)
+		return fmt.Errorf("failed to increment counter: %w", err)
 	}
+	d.Count = count
+	d.Remaining = max(d.Limit-int(count), 0)
 
 	// 3. Verifica se excedeu o limite
-	if int(count) > limit {
+	if int(count) > d.Limit {
 		// Bloqueia por X tempo
-		if err := rl.storage.Block(ctx, key, blockDuration); err != nil {
-			return false, fmt.Errorf("failed to block key: %w", err)
+		if err := rl.storage.Block(ctx, key, d.BlockDuration); err != nil {
+			return fmt.Errorf("failed to block key: %w", err)
 		}
-		return false, nil
+		d.Outcome = OutcomeDenied
+		d.BlockedUntil = rl.clock.Now().Add(d.BlockDuration)
+		return nil
 	}
 
-	return true, nil
+	d.Outcome = OutcomeAllowed
+	return nil
 }
 
-// Allow verifica IP ou Token (token tem precedência)
-func (rl *RateLimiter) Allow(ctx context.Context, ip, token string) (bool, error) {
-	if token != "" {
-		return rl.AllowToken(ctx, token)
+// BlockedKey é um IP/token com bloqueio ativo
+type BlockedKey struct {
+	Kind         string
+	ID           string
+	BlockedUntil time.Time
+}
+
+func (rl *RateLimiter) key(kind, id string) (string, error) {
+	if _, ok := rl.policies[kind]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+	}
+
+	return rl.keys.Key(kind, id), nil
+}
+
+// Clock retorna o relógio do limiter, para quem calcula esperas a partir de State
+func (rl *RateLimiter) Clock() clock.Clock {
+	return rl.clock
+}
+
+// State retorna o estado atual do IP/token no storage
+func (rl *RateLimiter) State(ctx context.Context, kind, id string) (storage.State, error) {
+	key, err := rl.key(kind, id)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	return rl.storage.GetState(ctx, key)
+}
+
+// Block bloqueia manualmente o IP/token pela duração informada
+func (rl *RateLimiter) Block(ctx context.Context, kind, id string, duration time.Duration) error {
+	key, err := rl.key(kind, id)
+	if err != nil {
+		return err
+	}
+
+	if err := rl.storage.Block(ctx, key, duration); err != nil {
+		return err
+	}
+
+	rl.notifyBlock(ctx, kind, id, rl.clock.Now().Add(duration))
+	return nil
+}
+
+func (rl *RateLimiter) Unblock(ctx context.Context, kind, id string) error {
+	return rl.clearBlock(ctx, kind, id, rl.storage.Unblock)
+}
+
+// Reset remove o contador e o bloqueio do IP/token
+func (rl *RateLimiter) Reset(ctx context.Context, kind, id string) error {
+	return rl.clearBlock(ctx, kind, id, rl.storage.Reset)
+}
+
+// clearBlock aplica Unblock/Reset e notifica os observers se a chave estava bloqueada
+func (rl *RateLimiter) clearBlock(ctx context.Context, kind, id string, clear func(ctx context.Context, key string) error) error {
+	key, err := rl.key(kind, id)
+	if err != nil {
+		return err
+	}
+
+	state, err := rl.storage.GetState(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if err := clear(ctx, key); err != nil {
+		return err
+	}
+
+	if state.Blocked() {
+		rl.notifyUnblock(ctx, kind, id)
+	}
+	return nil
+}
+
+// ListBlocked lista os IPs/tokens bloqueados deste prefixo/namespace
+func (rl *RateLimiter) ListBlocked(ctx context.Context) ([]BlockedKey, error) {
+	keys, err := rl.storage.ListBlocked(ctx, rl.keys.Prefix())
+	if err != nil {
+		return nil, err
 	}
 
-	return rl.AllowIP(ctx, ip)
+	blocked := make([]BlockedKey, 0, len(keys))
+	for _, k := range keys {
+		kind, id, ok := rl.keys.Parse(k.Key)
+		if !ok {
+			continue
+		}
+		blocked = append(blocked, BlockedKey{Kind: kind, ID: id, BlockedUntil: k.BlockedUntil})
+	}
+
+	sort.Slice(blocked, func(i, j int) bool {
+		return blocked[i].BlockedUntil.Before(blocked[j].BlockedUntil)
+	})
+
+	return blocked, nil
 }
@@ -0,0 +1,85 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+// failingStorage simula um storage indisponível
+type failingStorage struct {
+	storage.Storage
+}
+
+func (failingStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+type recordingObserver struct {
+	decisions []Decision
+}
+
+func (o *recordingObserver) ObserveDecision(ctx context.Context, d Decision) {
+	o.decisions = append(o.decisions, d)
+}
+
+// newTestLimiter cria o limiter com 2 req/s por IP e 5 req/s por token
+func newTestLimiter(store storage.Storage, opts ...Option) *RateLimiter {
+	base := []Option{WithIPLimit(2, time.Minute), WithTokenLimit(5, time.Minute)}
+	return New(store, append(base, opts...)...)
+}
+
+func TestRateLimiter_FailOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		failOpen    bool
+		wantAllowed bool
+		wantErr     bool
+	}{
+		{name: "fails closed by default", failOpen: false, wantAllowed: false, wantErr: true},
+		{name: "fails open when enabled", failOpen: true, wantAllowed: true, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := newTestLimiter(failingStorage{}, WithFailOpen(tt.failOpen))
+
+			allowed, err := rl.Allow(context.Background(), "1.2.3.4", "")
+			if allowed != tt.wantAllowed || (err != nil) != tt.wantErr {
+				t.Errorf("expected allowed=%v err=%v, got allowed=%v err=%v", tt.wantAllowed, tt.wantErr, allowed, err)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_NotifiesObservers(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	observer := &recordingObserver{}
+	rl := newTestLimiter(store, WithObserver(observer))
+
+	for i := 0; i < 4; i++ {
+		rl.Allow(context.Background(), "1.2.3.4", "")
+	}
+
+	want := []Outcome{OutcomeAllowed, OutcomeAllowed, OutcomeDenied, OutcomeBlocked}
+	if len(observer.decisions) != len(want) {
+		t.Fatalf("expected %d decisions, got %d", len(want), len(observer.decisions))
+	}
+	for i, d := range observer.decisions {
+		if d.Outcome != want[i] {
+			t.Errorf("decision %d: expected outcome %s, got %s", i+1, want[i], d.Outcome)
+		}
+	}
+
+	if remaining := observer.decisions[0].Remaining; remaining != 1 {
+		t.Errorf("expected 1 remaining after the first request, got %d", remaining)
+	}
+	if observer.decisions[2].BlockedUntil.IsZero() {
+		t.Error("expected denied decision to report when the block ends")
+	}
+}
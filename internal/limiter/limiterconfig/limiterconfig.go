@@ -0,0 +1,36 @@
+// Package limiterconfig monta o limiter a partir da configuração do serviço;
+// fica fora de limiter para que a API pública não dependa de internal/config.
+package limiterconfig
+
+import (
+	"fmt"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+// New cria o limiter com os limites e o prefixo da configuração; opts são
+// aplicadas depois e podem substituí-los
+func New(store storage.Storage, cfg *config.Config, opts ...limiter.Option) *limiter.RateLimiter {
+	base := []limiter.Option{
+		limiter.WithIPLimit(cfg.IpLimitRps, cfg.IpBlockDuration),
+		limiter.WithTokenLimit(cfg.TokenLimitRps, cfg.TokenBlockDuration),
+		limiter.WithKeyPrefix(cfg.KeyPrefix, cfg.KeyNamespace),
+	}
+	return limiter.New(store, append(base, opts...)...)
+}
+
+// PolicyOptions converte as políticas de POLICIES em opções para New
+func PolicyOptions(cfg *config.Config) ([]limiter.Option, error) {
+	policies, err := limiter.ParsePolicies(cfg.Policies)
+	if err != nil {
+		return nil, fmt.Errorf("POLICIES: %w", err)
+	}
+
+	opts := make([]limiter.Option, 0, len(policies))
+	for _, p := range policies {
+		opts = append(opts, limiter.WithPolicy(p))
+	}
+	return opts, nil
+}
@@ -0,0 +1,115 @@
+package limiter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy é um limite nomeado: no máximo Limit unidades de custo por Window, e
+// bloqueio por BlockDuration ao exceder. As políticas "ip" e "token" vêm de
+// WithIPLimit/WithTokenLimit (ou da configuração); outras são registradas com
+// WithPolicy.
+type Policy struct {
+	Name          string
+	Limit         int
+	Window        time.Duration
+	BlockDuration time.Duration
+}
+
+var ErrInvalidCost = errors.New("cost must be positive")
+
+var policyNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Validate checa o nome, o limite e as durações da política
+func (p Policy) Validate() error {
+	if !policyNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid policy name %q: use lowercase letters, digits, '-' and '_'", p.Name)
+	}
+	if p.Limit <= 0 {
+		return fmt.Errorf("policy %s: limit must be positive", p.Name)
+	}
+	if p.Window <= 0 || p.BlockDuration <= 0 {
+		return fmt.Errorf("policy %s: window and block duration must be positive", p.Name)
+	}
+	return nil
+}
+
+// WithPolicy registra uma política adicional; uma política com o nome "ip" ou
+// "token" substitui a padrão
+func WithPolicy(p Policy) Option {
+	return func(rl *RateLimiter) {
+		rl.policies[p.Name] = p
+	}
+}
+
+// ParsePolicies interpreta as políticas no formato "nome=limite/janela[/bloqueio]",
+// ex.: "login=5/1m/15m". Sem bloqueio explícito, a chave fica bloqueada pelo
+// restante de uma janela.
+func ParsePolicies(specs []string) ([]Policy, error) {
+	policies := make([]Policy, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+
+	for _, spec := range specs {
+		name, rule, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid policy %q, expected name=limit/window[/block]", spec)
+		}
+
+		parts := strings.Split(rule, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid policy %q, expected name=limit/window[/block]", spec)
+		}
+
+		limit, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: invalid limit %q", name, parts[0])
+		}
+		window, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: invalid window %q", name, parts[1])
+		}
+		block := window
+		if len(parts) == 3 {
+			if block, err = time.ParseDuration(parts[2]); err != nil {
+				return nil, fmt.Errorf("policy %s: invalid block duration %q", name, parts[2])
+			}
+		}
+
+		p := Policy{Name: name, Limit: limit, Window: window, BlockDuration: block}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate policy %q", name)
+		}
+		seen[name] = true
+
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// Policy retorna a política registrada com o nome informado
+func (rl *RateLimiter) Policy(name string) (Policy, bool) {
+	p, ok := rl.policies[name]
+	return p, ok
+}
+
+// Policies lista as políticas registradas, ordenadas por nome
+func (rl *RateLimiter) Policies() []Policy {
+	policies := make([]Policy, 0, len(rl.policies))
+	for _, p := range rl.policies {
+		policies = append(policies, p)
+	}
+
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return policies
+}
@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies([]string{"login=5/1m/15m", "search=100/1s"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Policy{
+		{Name: "login", Limit: 5, Window: time.Minute, BlockDuration: 15 * time.Minute},
+		{Name: "search", Limit: 100, Window: time.Second, BlockDuration: time.Second},
+	}
+	if len(policies) != len(want) {
+		t.Fatalf("expected %d policies, got %d", len(want), len(policies))
+	}
+	for i := range want {
+		if policies[i] != want[i] {
+			t.Errorf("policy %d: expected %+v, got %+v", i, want[i], policies[i])
+		}
+	}
+
+	invalid := []string{
+		"login",
+		"login=5",
+		"login=five/1m",
+		"login=5/soon",
+		"login=0/1m",
+		"Login=5/1m",
+		"log:in=5/1m",
+		"login=5/1m/-1s",
+	}
+	for _, spec := range invalid {
+		if _, err := ParsePolicies([]string{spec}); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+
+	if _, err := ParsePolicies([]string{"login=5/1m", "login=10/1m"}); err == nil {
+		t.Error("expected error for duplicate policy")
+	}
+}
+
+func TestRateLimiter_DecidePolicyWithCost(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	store := storage.NewMemoryStorage(storage.WithMemoryClock(clk))
+	t.Cleanup(func() { store.Close() })
+
+	rl := newTestLimiter(store, WithClock(clk),
+		WithPolicy(Policy{Name: "export", Limit: 10, Window: time.Minute, BlockDuration: 5 * time.Minute}))
+	ctx := context.Background()
+
+	steps := []struct {
+		cost      int
+		outcome   Outcome
+		remaining int
+	}{
+		{4, OutcomeAllowed, 6},
+		{6, OutcomeAllowed, 0},
+		{1, OutcomeDenied, 0},
+		{1, OutcomeBlocked, 0},
+	}
+	for i, step := range steps {
+		d, err := rl.DecidePolicy(ctx, "export", "user-1", step.cost)
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i+1, err)
+		}
+		if d.Outcome != step.outcome || d.Remaining != step.remaining {
+			t.Errorf("step %d: expected %s with %d remaining, got %s with %d", i+1, step.outcome, step.remaining, d.Outcome, d.Remaining)
+		}
+	}
+
+	// o contador de cada política é separado
+	if d, _ := rl.DecideIP(ctx, "user-1"); d.Outcome != OutcomeAllowed {
+		t.Errorf("expected ip policy to be independent, got %s", d.Outcome)
+	}
+
+	// a janela da política (1m) é respeitada
+	clk.Advance(5*time.Minute + time.Second)
+	if d, _ := rl.DecidePolicy(ctx, "export", "user-1", 10); d.Outcome != OutcomeAllowed || d.Count != 10 {
+		t.Errorf("expected a fresh window after the block, got %+v", d)
+	}
+
+	// políticas customizadas também aceitam as operações de administração
+	if err := rl.Block(ctx, "export", "user-2", time.Minute); err != nil {
+		t.Errorf("expected block on custom policy to succeed, got %v", err)
+	}
+}
+
+func TestRateLimiter_DecidePolicyKeyKind(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := newTestLimiter(store, WithPolicy(Policy{Name: "orders", Limit: 10, Window: time.Minute, BlockDuration: time.Minute}))
+	ctx := context.Background()
+
+	tests := []struct {
+		policy, id string
+		kind       string
+	}{
+		{"orders", ClientID("abc", "1.2.3.4"), KindToken},
+		{"orders", ClientID("", "1.2.3.4"), KindIP},
+		{"orders", "user-1", "orders"},
+		{KindIP, "1.2.3.4", KindIP},
+	}
+	for _, tt := range tests {
+		d, err := rl.DecidePolicy(ctx, tt.policy, tt.id, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if d.Kind != tt.kind || d.Policy != tt.policy {
+			t.Errorf("%s/%s: expected kind %q and policy %q, got %q and %q", tt.policy, tt.id, tt.kind, tt.policy, d.Kind, d.Policy)
+		}
+	}
+
+	// o contador continua sendo o da política
+	if state, _ := rl.State(ctx, "orders", ClientID("abc", "1.2.3.4")); state.Count != 1 {
+		t.Errorf("expected the orders counter to be 1, got %d", state.Count)
+	}
+}
+
+func TestRateLimiter_DecidePolicyRejectsInvalidInput(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := newTestLimiter(store)
+
+	if _, err := rl.DecidePolicy(context.Background(), "unknown", "x", 1); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("expected ErrUnknownKind, got %v", err)
+	}
+	if _, err := rl.DecidePolicy(context.Background(), KindIP, "x", 0); !errors.Is(err, ErrInvalidCost) {
+		t.Errorf("expected ErrInvalidCost, got %v", err)
+	}
+}
+
+func TestRateLimiter_PeekDoesNotConsume(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := newTestLimiter(store,
+		WithPolicy(Policy{Name: "export", Limit: 10, Window: time.Minute, BlockDuration: 5 * time.Minute}))
+	ctx := context.Background()
+
+	if _, err := rl.DecidePolicy(ctx, "export", "user-1", 6); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d, err := rl.Peek(ctx, "export", "user-1", 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Outcome != OutcomeAllowed || d.Remaining != 0 {
+		t.Errorf("expected allowed with 0 remaining, got %s with %d", d.Outcome, d.Remaining)
+	}
+
+	if d, _ := rl.Peek(ctx, "export", "user-1", 5); d.Outcome != OutcomeDenied {
+		t.Errorf("expected denied, got %s", d.Outcome)
+	}
+
+	// nada foi consumido nem bloqueado pelas consultas
+	state, err := rl.State(ctx, "export", "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Count != 6 || state.Blocked() {
+		t.Errorf("expected count 6 and no block, got %+v", state)
+	}
+
+	if err := rl.Block(ctx, "export", "user-1", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d, _ := rl.Peek(ctx, "export", "user-1", 1); d.Outcome != OutcomeBlocked {
+		t.Errorf("expected blocked, got %s", d.Outcome)
+	}
+}
@@ -0,0 +1,145 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrExceedsLimit indica um custo maior que o limite da política, que nunca
+	// caberia em uma janela
+	ErrExceedsLimit = errors.New("cost exceeds policy limit")
+	// ErrWaitExceedsDeadline indica que a capacidade só voltaria depois do
+	// deadline do contexto
+	ErrWaitExceedsDeadline = errors.New("wait would exceed context deadline")
+)
+
+// minWait evita espera ativa quando a janela termina logo depois da reserva recusada
+const minWait = 10 * time.Millisecond
+
+// Reservation é o resultado de Reserve. Diferente de golang.org/x/time/rate, a
+// capacidade só é reservada na janela atual: sem capacidade, OK é falso e Delay
+// diz quando tentar de novo.
+type Reservation struct {
+	rl          *RateLimiter
+	key         string
+	window      time.Duration
+	cost        int
+	ok          bool
+	delay       time.Duration
+	windowStart time.Time
+}
+
+// OK indica se o custo foi consumido
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay é quanto esperar até haver capacidade; zero quando OK
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel devolve o custo reservado, se a janela em que ele foi consumido ainda
+// não terminou
+func (r *Reservation) Cancel(ctx context.Context) error {
+	if !r.ok || r.windowStart.IsZero() {
+		return nil
+	}
+	r.ok = false
+
+	if err := r.rl.storage.Refund(ctx, r.key, int64(r.cost), r.windowStart); err != nil {
+		return fmt.Errorf("failed to return reservation: %w", err)
+	}
+	return nil
+}
+
+// Reserve consome n unidades da chave na política se couberem na janela atual.
+// Ao contrário de DecidePolicy, exceder o limite não bloqueia a chave: o
+// consumo é desfeito e a reserva informa quanto esperar.
+func (rl *RateLimiter) Reserve(ctx context.Context, policy, id string, n int) (*Reservation, error) {
+	d, err := rl.partial(policy, id, n)
+	if err != nil {
+		return nil, err
+	}
+	if n > d.Limit {
+		return nil, fmt.Errorf("%w: %d > %d", ErrExceedsLimit, n, d.Limit)
+	}
+
+	r := &Reservation{rl: rl, key: rl.keys.Key(d.Policy, d.ID), window: d.Window, cost: n}
+
+	if err := rl.reserve(ctx, r, d.Limit); err != nil {
+		if !rl.failOpen {
+			return nil, err
+		}
+		d.Outcome = OutcomeFailOpen
+		rl.notify(ctx, d)
+		// sem storage não há o que devolver em Cancel
+		return &Reservation{ok: true}, nil
+	}
+
+	if r.ok {
+		d.Outcome = OutcomeAllowed
+		rl.notify(ctx, d)
+	}
+	return r, nil
+}
+
+// reserve consome o custo só se ele couber, em uma única operação do storage:
+// uma requisição concorrente nunca vê o contador acima do limite por causa de
+// uma reserva recusada
+func (rl *RateLimiter) reserve(ctx context.Context, r *Reservation, limit int) error {
+	state, ok, err := rl.storage.Take(ctx, r.key, int64(r.cost), int64(limit), r.window)
+	if err != nil {
+		return fmt.Errorf("failed to reserve: %w", err)
+	}
+
+	switch {
+	case ok:
+		r.ok = true
+		r.windowStart = state.WindowStart
+	case state.Blocked():
+		r.delay = rl.until(state.BlockedUntil)
+	default:
+		r.delay = rl.until(state.WindowEnd)
+	}
+	return nil
+}
+
+func (rl *RateLimiter) until(t time.Time) time.Duration {
+	return max(t.Sub(rl.clock.Now()), minWait)
+}
+
+// Wait espera até poder consumir uma unidade da chave na política
+func (rl *RateLimiter) Wait(ctx context.Context, policy, id string) error {
+	return rl.WaitN(ctx, policy, id, 1)
+}
+
+// WaitN espera até poder consumir n unidades, ou até o contexto terminar.
+// Retorna ErrWaitExceedsDeadline sem esperar se a capacidade só voltaria depois
+// do deadline.
+func (rl *RateLimiter) WaitN(ctx context.Context, policy, id string, n int) error {
+	for {
+		r, err := rl.Reserve(ctx, policy, id, n)
+		if err != nil {
+			return err
+		}
+		if r.OK() {
+			return nil
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && rl.clock.Now().Add(r.Delay()).After(deadline) {
+			return ErrWaitExceedsDeadline
+		}
+
+		timer := time.NewTimer(r.Delay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func TestRateLimiter_ReserveAndCancel(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	store := storage.NewMemoryStorage(storage.WithMemoryClock(clk))
+	t.Cleanup(func() { store.Close() })
+
+	rl := newTestLimiter(store, WithClock(clk),
+		WithPolicy(Policy{Name: "jobs", Limit: 10, Window: time.Minute, BlockDuration: time.Minute}))
+	ctx := context.Background()
+
+	first, err := rl.Reserve(ctx, "jobs", "worker", 8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !first.OK() || first.Delay() != 0 {
+		t.Fatalf("expected an immediate reservation, got ok=%v delay=%v", first.OK(), first.Delay())
+	}
+
+	clk.Advance(20 * time.Second)
+	second, err := rl.Reserve(ctx, "jobs", "worker", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.OK() || second.Delay() != 40*time.Second {
+		t.Errorf("expected to wait for the end of the window, got ok=%v delay=%v", second.OK(), second.Delay())
+	}
+
+	// exceder na reserva não bloqueia a chave nem consome o limite
+	state, _ := rl.State(ctx, "jobs", "worker")
+	if state.Blocked() || state.Count != 8 {
+		t.Errorf("expected count 8 and no block, got %+v", state)
+	}
+
+	if err := first.Cancel(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if third, _ := rl.Reserve(ctx, "jobs", "worker", 10); !third.OK() {
+		t.Error("expected the cancelled reservation to free capacity")
+	}
+
+	// cancelar de novo não devolve o custo duas vezes
+	if err := first.Cancel(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state, _ := rl.State(ctx, "jobs", "worker"); state.Count != 10 {
+		t.Errorf("expected count 10, got %d", state.Count)
+	}
+
+	if _, err := rl.Reserve(ctx, "jobs", "worker", 11); !errors.Is(err, ErrExceedsLimit) {
+		t.Errorf("expected ErrExceedsLimit, got %v", err)
+	}
+
+	// com a chave bloqueada a espera é até o fim do bloqueio
+	if err := rl.Block(ctx, "jobs", "other", 90*time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r, _ := rl.Reserve(ctx, "jobs", "other", 1); r.OK() || r.Delay() != 90*time.Second {
+		t.Errorf("expected to wait for the block, got ok=%v delay=%v", r.OK(), r.Delay())
+	}
+}
+
+func TestRateLimiter_WaitN(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := newTestLimiter(store,
+		WithPolicy(Policy{Name: "jobs", Limit: 2, Window: 200 * time.Millisecond, BlockDuration: time.Minute}))
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := rl.Wait(ctx, "jobs", "worker"); err != nil {
+			t.Fatalf("wait %d: unexpected error: %v", i+1, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the third wait to block until the next window, took %v", elapsed)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := rl.WaitN(short, "jobs", "worker", 2); !errors.Is(err, ErrWaitExceedsDeadline) {
+		t.Errorf("expected ErrWaitExceedsDeadline, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := rl.WaitN(cancelled, "jobs", "worker", 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
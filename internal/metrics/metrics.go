@@ -0,0 +1,199 @@
+// Package metrics expõe as métricas Prometheus do rate limiter. Nenhuma métrica
+// usa IPs ou tokens como label, mantendo a cardinalidade limitada.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/offenders"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ratelimiter"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	decisions      *prometheus.CounterVec
+	failOpen       prometheus.Counter
+	storageLatency *prometheus.HistogramVec
+	storageErrors  *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "decisions_total",
+			Help:      "Rate limit decisions by key type, policy and outcome (allowed, denied, blocked, fail_open).",
+		}, []string{"type", "policy", "outcome"}),
+		failOpen: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fail_open_total",
+			Help:      "Requests allowed because the storage failed and fail-open is enabled.",
+		}),
+		storageLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage operation latency by operation.",
+			Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_errors_total",
+			Help:      "Storage operation errors by operation.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.decisions,
+		m.failOpen,
+		m.storageLatency,
+		m.storageErrors,
+	)
+
+	return m
+}
+
+func (m *Metrics) ObserveDecision(ctx context.Context, d limiter.Decision) {
+	m.decisions.WithLabelValues(d.Kind, d.Policy, string(d.Outcome)).Inc()
+
+	if d.Outcome == limiter.OutcomeFailOpen {
+		m.failOpen.Inc()
+	}
+}
+
+// RegisterBlockedKeys adiciona o gauge com o número de chaves bloqueadas. A
+// listagem percorre o storage inteiro, então é reaproveitada pelos scrapes
+// durante ttl; ttl 0 lista a cada scrape.
+func (m *Metrics) RegisterBlockedKeys(rl *limiter.RateLimiter, ttl time.Duration) {
+	m.registry.MustRegister(&blockedKeysCollector{
+		rl:  rl,
+		ttl: ttl,
+		now: time.Now,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "blocked_keys"),
+			"Keys currently blocked, by key type.",
+			[]string{"type"}, nil,
+		),
+	})
+}
+
+// RegisterTopOffenders adiciona o gauge com as contagens do top n de cada métrica
+// do tracker. O label é só a posição no ranking, nunca o IP/token.
+func (m *Metrics) RegisterTopOffenders(t offenders.Tracker, n int) {
+	m.registry.MustRegister(&topOffendersCollector{
+		tracker: t,
+		n:       n,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "top_offender_count"),
+			"Estimated count of the top offenders in the tracking window, by metric (requests, denials) and rank.",
+			[]string{"metric", "rank"}, nil,
+		),
+	})
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) observeStorage(operation string, start time.Time, err error) {
+	m.storageLatency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+type blockedKeysCollector struct {
+	rl   *limiter.RateLimiter
+	ttl  time.Duration
+	now  func() time.Time
+	desc *prometheus.Desc
+
+	// mu também serializa scrapes simultâneos, que esperam a mesma listagem
+	mu        sync.Mutex
+	counts    map[string]int
+	fetchedAt time.Time
+}
+
+func (c *blockedKeysCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *blockedKeysCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.blockedCounts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	for kind, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), kind)
+	}
+}
+
+// blockedCounts retorna as contagens em cache ou lista os bloqueios de novo
+// quando o ttl expirou; erros não são guardados, o próximo scrape tenta de novo
+func (c *blockedKeysCollector) blockedCounts() (map[string]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.counts != nil && now.Sub(c.fetchedAt) < c.ttl {
+		return c.counts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	blocked, err := c.rl.ListBlocked(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{limiter.KindIP: 0, limiter.KindToken: 0}
+	for _, b := range blocked {
+		counts[b.Kind]++
+	}
+
+	c.counts = counts
+	c.fetchedAt = now
+	return counts, nil
+}
+
+type topOffendersCollector struct {
+	tracker offenders.Tracker
+	n       int
+	desc    *prometheus.Desc
+}
+
+func (c *topOffendersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *topOffendersCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, metric := range offenders.Metrics {
+		entries, err := c.tracker.Top(ctx, metric, c.n)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.desc, err)
+			return
+		}
+
+		for i, e := range entries {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(e.Count), string(metric), strconv.Itoa(i+1))
+		}
+	}
+}
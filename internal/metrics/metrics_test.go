@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/offenders"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		IpLimitRps:         2,
+		IpBlockDuration:    time.Minute,
+		TokenLimitRps:      5,
+		TokenBlockDuration: time.Minute,
+		KeyPrefix:          "ratelimiter",
+	}
+}
+
+// failingStorage simula um storage indisponível
+type failingStorage struct {
+	storage.Storage
+}
+
+func (failingStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestMetrics_CountsDecisionsByOutcome(t *testing.T) {
+	m := New()
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	tracker := offenders.NewLocal()
+	rl := limiterconfig.New(m.InstrumentStorage(store), testConfig(), limiter.WithObserver(m), limiter.WithObserver(tracker))
+	m.RegisterBlockedKeys(rl, 0)
+	m.RegisterTopOffenders(tracker, offenders.DefaultTop)
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		rl.Allow(ctx, "1.2.3.4", "")
+	}
+
+	tests := []struct {
+		outcome limiter.Outcome
+		want    float64
+	}{
+		{limiter.OutcomeAllowed, 2},
+		{limiter.OutcomeDenied, 1},
+		{limiter.OutcomeBlocked, 2},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(m.decisions.WithLabelValues(limiter.KindIP, limiter.KindIP, string(tt.outcome)))
+		if got != tt.want {
+			t.Errorf("outcome %s: expected %v, got %v", tt.outcome, tt.want, got)
+		}
+	}
+
+	if got := testutil.CollectAndCount(m.storageLatency); got == 0 {
+		t.Error("expected storage latency to be observed")
+	}
+
+	server := httptest.NewServer(m.Handler())
+	t.Cleanup(server.Close)
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to scrape metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `ratelimiter_blocked_keys{type="ip"} 1`) {
+		t.Errorf("expected blocked keys gauge in scrape output, got:\n%s", body)
+	}
+	if !strings.Contains(string(body), `ratelimiter_top_offender_count{metric="denials",rank="1"} 3`) {
+		t.Errorf("expected top offenders gauge in scrape output, got:\n%s", body)
+	}
+	if strings.Contains(string(body), "1.2.3.4") {
+		t.Error("expected raw IPs never to be exposed as labels")
+	}
+}
+
+func TestMetrics_CountsFailOpenAndStorageErrors(t *testing.T) {
+	m := New()
+
+	rl := limiterconfig.New(m.InstrumentStorage(failingStorage{}), testConfig(),
+		limiter.WithObserver(m),
+		limiter.WithFailOpen(true),
+	)
+
+	allowed, err := rl.Allow(context.Background(), "1.2.3.4", "")
+	if err != nil || !allowed {
+		t.Fatalf("expected request to be allowed with fail-open, got allowed=%v err=%v", allowed, err)
+	}
+
+	if got := testutil.ToFloat64(m.failOpen); got != 1 {
+		t.Errorf("expected fail open count 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.storageErrors.WithLabelValues("is_blocked")); got != 1 {
+		t.Errorf("expected 1 storage error, got %v", got)
+	}
+}
+
+// countingStorage conta as listagens de bloqueios
+type countingStorage struct {
+	storage.Storage
+	lists atomic.Int32
+}
+
+func (s *countingStorage) ListBlocked(ctx context.Context, prefix string) ([]storage.BlockedKey, error) {
+	s.lists.Add(1)
+	return s.Storage.ListBlocked(ctx, prefix)
+}
+
+func TestBlockedKeysCollector_CachesBetweenScrapes(t *testing.T) {
+	mem := storage.NewMemoryStorage()
+	t.Cleanup(func() { mem.Close() })
+	store := &countingStorage{Storage: mem}
+
+	rl := limiterconfig.New(store, testConfig())
+	if err := rl.Block(context.Background(), limiter.KindIP, "1.2.3.4", time.Minute); err != nil {
+		t.Fatalf("failed to block: %v", err)
+	}
+
+	now := time.Now()
+	c := &blockedKeysCollector{
+		rl:   rl,
+		ttl:  30 * time.Second,
+		now:  func() time.Time { return now },
+		desc: prometheus.NewDesc("ratelimiter_blocked_keys", "test", []string{"type"}, nil),
+	}
+
+	for range 3 {
+		if got := testutil.CollectAndCount(c); got != 2 {
+			t.Fatalf("expected 2 series, got %d", got)
+		}
+	}
+	if got := store.lists.Load(); got != 1 {
+		t.Errorf("expected 1 listing within the ttl, got %d", got)
+	}
+
+	now = now.Add(31 * time.Second)
+	testutil.CollectAndCount(c)
+	if got := store.lists.Load(); got != 2 {
+		t.Errorf("expected a new listing after the ttl, got %d", got)
+	}
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+// InstrumentStorage mede a latência e os erros de cada operação do storage
+func (m *Metrics) InstrumentStorage(s storage.Storage) storage.Storage {
+	return &instrumentedStorage{next: s, metrics: m}
+}
+
+type instrumentedStorage struct {
+	next    storage.Storage
+	metrics *Metrics
+}
+
+func (s *instrumentedStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	start := time.Now()
+	count, err := s.next.Increment(ctx, key, window)
+	s.metrics.observeStorage("increment", start, err)
+	return count, err
+}
+
+func (s *instrumentedStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	start := time.Now()
+	count, err := s.next.IncrementBy(ctx, key, n, window)
+	s.metrics.observeStorage("increment", start, err)
+	return count, err
+}
+
+func (s *instrumentedStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (storage.State, bool, error) {
+	start := time.Now()
+	state, ok, err := s.next.Take(ctx, key, n, limit, window)
+	s.metrics.observeStorage("take", start, err)
+	return state, ok, err
+}
+
+func (s *instrumentedStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
+	start := time.Now()
+	err := s.next.Refund(ctx, key, n, windowStart)
+	s.metrics.observeStorage("refund", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	blocked, err := s.next.IsBlocked(ctx, key)
+	s.metrics.observeStorage("is_blocked", start, err)
+	return blocked, err
+}
+
+func (s *instrumentedStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	start := time.Now()
+	err := s.next.Block(ctx, key, duration)
+	s.metrics.observeStorage("block", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Unblock(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Unblock(ctx, key)
+	s.metrics.observeStorage("unblock", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Reset(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Reset(ctx, key)
+	s.metrics.observeStorage("reset", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) GetState(ctx context.Context, key string) (storage.State, error) {
+	start := time.Now()
+	state, err := s.next.GetState(ctx, key)
+	s.metrics.observeStorage("get_state", start, err)
+	return state, err
+}
+
+func (s *instrumentedStorage) ListBlocked(ctx context.Context, prefix string) ([]storage.BlockedKey, error) {
+	start := time.Now()
+	keys, err := s.next.ListBlocked(ctx, prefix)
+	s.metrics.observeStorage("list_blocked", start, err)
+	return keys, err
+}
+
+func (s *instrumentedStorage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	s.metrics.observeStorage("ping", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Close() error {
+	return s.next.Close()
+}
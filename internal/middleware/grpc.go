@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// GRPCKeyFunc escolhe a política e o ID limitados em uma chamada gRPC. Uma
+// política vazia usa a regra do middleware HTTP: api-key, ou o IP do cliente.
+type GRPCKeyFunc func(ctx context.Context, fullMethod string) (policy, id string)
+
+type grpcOptions struct {
+	keyFunc  GRPCKeyFunc
+	policies map[string]string
+	skip     map[string]bool
+	trusted  TrustedProxies
+}
+
+type GRPCOption func(*grpcOptions)
+
+// WithGRPCKeyFunc substitui a extração de chave padrão
+func WithGRPCKeyFunc(fn GRPCKeyFunc) GRPCOption {
+	return func(o *grpcOptions) {
+		o.keyFunc = fn
+	}
+}
+
+// WithGRPCMethodPolicy aplica a política a um método ("/pkg.Service/Method") ou
+// a todos os métodos de um serviço ("/pkg.Service/"). A chave continua sendo a
+// api-key ou o IP do cliente.
+func WithGRPCMethodPolicy(method, policy string) GRPCOption {
+	return func(o *grpcOptions) {
+		o.policies[method] = policy
+	}
+}
+
+// WithGRPCTrustedProxies faz o interceptor ler o IP do cliente dos metadata
+// x-forwarded-for/x-real-ip quando a conexão vem de um desses proxies
+func WithGRPCTrustedProxies(trusted TrustedProxies) GRPCOption {
+	return func(o *grpcOptions) {
+		o.trusted = trusted
+	}
+}
+
+// WithGRPCSkip deixa os métodos informados fora do rate limit (ex.: health checks)
+func WithGRPCSkip(methods ...string) GRPCOption {
+	return func(o *grpcOptions) {
+		for _, m := range methods {
+			o.skip[m] = true
+		}
+	}
+}
+
+// UnaryServerInterceptor aplica o rate limit às chamadas unárias. Chamadas
+// negadas recebem codes.ResourceExhausted com RetryInfo.
+func UnaryServerInterceptor(rl *limiter.RateLimiter, opts ...GRPCOption) grpc.UnaryServerInterceptor {
+	g := newGRPCLimiter(rl, opts)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, header, err := g.check(ctx, info.FullMethod)
+		if header != nil {
+			grpc.SetHeader(ctx, header)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor aplica o rate limit na abertura de cada stream; as
+// mensagens do stream não consomem o limite
+func StreamServerInterceptor(rl *limiter.RateLimiter, opts ...GRPCOption) grpc.StreamServerInterceptor {
+	g := newGRPCLimiter(rl, opts)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, header, err := g.check(ss.Context(), info.FullMethod)
+		if header != nil {
+			ss.SetHeader(header)
+		}
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type grpcLimiter struct {
+	rl    *limiter.RateLimiter
+	route *RouteLimiter
+	grpcOptions
+}
+
+func newGRPCLimiter(rl *limiter.RateLimiter, opts []GRPCOption) *grpcLimiter {
+	g := &grpcLimiter{
+		rl: rl,
+		grpcOptions: grpcOptions{
+			policies: make(map[string]string),
+			skip:     make(map[string]bool),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&g.grpcOptions)
+	}
+
+	// a decisão é a mesma do middleware HTTP, com as políticas por método como rotas
+	g.route = NewRouteLimiter(rl)
+	g.route.routes = g.policies
+
+	return g
+}
+
+// check decide a chamada e retorna o contexto com os dados da requisição, os
+// headers de limite e o erro gRPC a devolver ao cliente, se houver
+func (g *grpcLimiter) check(ctx context.Context, fullMethod string) (context.Context, metadata.MD, error) {
+	if g.skip[fullMethod] {
+		return ctx, nil, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	req := Request{
+		ID:     first(md, "x-request-id"),
+		Method: "GRPC",
+		Path:   fullMethod,
+		Route:  g.methodRoute(fullMethod),
+		IP:     grpcClientIP(ctx, md, g.trusted),
+		Token:  first(md, "api-key"),
+	}
+	if g.keyFunc != nil {
+		req.Policy, req.ClientID = g.keyFunc(ctx, fullMethod)
+	}
+
+	ctx, _, decision, err := g.route.Decide(ctx, req)
+	if err != nil {
+		return ctx, nil, status.Error(codes.Internal, "rate limiter failed")
+	}
+
+	header := metadata.MD{}
+	switch decision.Outcome {
+	case limiter.OutcomeAllowed, limiter.OutcomeDenied:
+		header.Set("x-ratelimit-limit", strconv.Itoa(decision.Limit))
+		header.Set("x-ratelimit-remaining", strconv.Itoa(decision.Remaining))
+	}
+
+	if decision.Allowed() {
+		return ctx, header, nil
+	}
+
+	return ctx, header, g.exhausted(ctx, decision)
+}
+
+// exhausted monta o erro ResourceExhausted; para uma chave que já estava
+// bloqueada o fim do bloqueio é lido do storage
+func (g *grpcLimiter) exhausted(ctx context.Context, d limiter.Decision) error {
+	st := status.New(codes.ResourceExhausted, LimitExceededMessage)
+
+	delay := d.BlockDuration
+	if d.Outcome == limiter.OutcomeBlocked {
+		state, err := g.rl.State(ctx, d.Policy, d.ID)
+		if err != nil || !state.Blocked() {
+			return st.Err()
+		}
+		delay = state.BlockedUntil.Sub(g.rl.Clock().Now())
+	}
+
+	withInfo, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		return st.Err()
+	}
+	return withInfo.Err()
+}
+
+// methodRoute retorna a rota com política que casa com o método: o próprio
+// método ou, na falta dele, o serviço
+func (g *grpcLimiter) methodRoute(fullMethod string) string {
+	if _, ok := g.policies[fullMethod]; ok {
+		return fullMethod
+	}
+
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if _, ok := g.policies[fullMethod[:i+1]]; ok {
+			return fullMethod[:i+1]
+		}
+	}
+	return ""
+}
+
+// grpcClientIP usa o endereço da conexão; os metadata x-forwarded-for e
+// x-real-ip só valem quando ela vem de um proxy confiável
+func grpcClientIP(ctx context.Context, md metadata.MD, trusted TrustedProxies) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	return trusted.clientIP(hostIP(p.Addr.String()), md.Get("x-forwarded-for"), first(md, "x-real-ip"))
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream repassa ao handler o contexto com os dados da requisição
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCClient sobe um servidor com o serviço de health check atrás dos interceptors
+func newGRPCClient(t *testing.T, opts ...GRPCOption) healthpb.HealthClient {
+	t.Helper()
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{
+		IpLimitRps:         2,
+		IpBlockDuration:    time.Minute,
+		TokenLimitRps:      3,
+		TokenBlockDuration: time.Minute,
+		KeyPrefix:          "test",
+	}
+	rl := limiterconfig.New(store, cfg,
+		limiter.WithPolicy(limiter.Policy{Name: "watch", Limit: 1, Window: time.Minute, BlockDuration: time.Minute}))
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(rl, opts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(rl, opts...)),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func retryDelay(t *testing.T, err error) time.Duration {
+	t.Helper()
+
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	t.Fatalf("expected RetryInfo in %v", err)
+	return 0
+}
+
+func TestUnaryServerInterceptor_LimitsByIPAndToken(t *testing.T) {
+	client := newGRPCClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		var header metadata.MD
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header)); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i+1, err)
+		}
+		if got := header.Get("x-ratelimit-limit"); len(got) != 1 || got[0] != "2" {
+			t.Errorf("call %d: expected x-ratelimit-limit 2, got %v", i+1, got)
+		}
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	if delay := retryDelay(t, err); delay != time.Minute {
+		t.Errorf("expected retry delay of 1m, got %v", delay)
+	}
+
+	// a chave já bloqueada informa o tempo restante do bloqueio
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if delay := retryDelay(t, err); delay <= 0 || delay > time.Minute {
+		t.Errorf("expected remaining block time, got %v", delay)
+	}
+
+	// a api-key tem precedência sobre o IP bloqueado
+	tokenCtx := metadata.AppendToOutgoingContext(ctx, "api-key", "abc123")
+	if _, err := client.Check(tokenCtx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("expected token call to be allowed, got %v", err)
+	}
+}
+
+func TestGRPCClientIP_ForwardedForOnlyFromTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("parse trusted proxies: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		trusted TrustedProxies
+		peer    string
+		want    string
+	}{
+		{"no trusted proxies", nil, "10.0.0.1", "10.0.0.1"},
+		{"untrusted peer", trusted, "198.51.100.1", "198.51.100.1"},
+		{"trusted peer", trusted, "10.0.0.1", "203.0.113.7"},
+	}
+
+	md := metadata.Pairs("x-forwarded-for", "203.0.113.7", "x-real-ip", "203.0.113.8")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 50000}})
+			if got := grpcClientIP(ctx, md, tt.trusted); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamServerInterceptor_MethodPolicy(t *testing.T) {
+	client := newGRPCClient(t,
+		WithGRPCMethodPolicy("/grpc.health.v1.Health/Watch", "watch"),
+		WithGRPCSkip("/grpc.health.v1.Health/Check"),
+	)
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "api-key", "abc123"))
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("expected first stream to be allowed, got %v", err)
+	}
+
+	stream, err = client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	// métodos ignorados não consomem o limite
+	for i := 0; i < 5; i++ {
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("expected skipped method to be allowed, got %v", err)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_KeyFunc(t *testing.T) {
+	client := newGRPCClient(t, WithGRPCKeyFunc(func(ctx context.Context, _ string) (string, string) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return "watch", "tenant:" + first(md, "tenant")
+	}))
+	tenantA := metadata.AppendToOutgoingContext(context.Background(), "tenant", "a")
+	tenantB := metadata.AppendToOutgoingContext(context.Background(), "tenant", "b")
+
+	if _, err := client.Check(tenantA, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("expected first call to be allowed, got %v", err)
+	}
+	if _, err := client.Check(tenantA, &healthpb.HealthCheckRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	// a chave vem da GRPCKeyFunc, não do IP
+	if _, err := client.Check(tenantB, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("expected another tenant to be allowed, got %v", err)
+	}
+}
@@ -1,41 +1,70 @@
 package middleware
 
 import (
-	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
-	"net"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
 
 	"github.com/alexduzi/labratelimiter/internal/dto"
 	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func RateLimiter(rl *limiter.RateLimiter) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.Background()
+// LimitExceededMessage é a mensagem das respostas 429
+const LimitExceededMessage = "you have reached the maximum number of requests or actions allowed within a certain time frame"
+
+// RateLimiter limita as requisições de um http.Handler; com WithRoutePolicy, o
+// template da rota é o padrão do http.ServeMux (ex.: "GET /users/{id}")
+func RateLimiter(rl *limiter.RateLimiter, opts ...RouteOption) func(http.Handler) http.Handler {
+	l := NewRouteLimiter(rl, opts...)
 
-			// Extrai IP real
-			ip := getIP(r)
+	return func(next http.Handler) http.Handler {
+		return l.Middleware(func(r *http.Request) string { return servePattern(next, r) })(next)
+	}
+}
 
-			// Extrai token do header
-			token := r.Header.Get("API_KEY")
+// servePattern retorna o padrão do ServeMux da requisição; quando o middleware
+// envolve o próprio mux, r.Pattern ainda está vazio e o padrão é procurado nele
+func servePattern(next http.Handler, r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	if mux, ok := next.(*http.ServeMux); ok {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+	return ""
+}
 
-			// Verifica rate limit
-			allowed, err := rl.Allow(ctx, ip, token)
+// Middleware aplica o limiter a um http.Handler; route extrai o template da rota
+// da requisição (ex.: r.Pattern do http.ServeMux)
+func (l *RouteLimiter) Middleware(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reaproveita o X-Request-ID do cliente/proxy ou gera um novo
+			_, requestID, decision, err := l.Decide(r.Context(), Request{
+				ID:     r.Header.Get("X-Request-ID"),
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Route:  route(r),
+				IP:     l.trusted.ClientIP(r),
+				Token:  r.Header.Get("API_KEY"),
+			})
+			w.Header().Set("X-Request-ID", requestID)
 			if err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
 			}
 
-			if !allowed {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				response := dto.ResponseMessage{
-					Message: "you have reached the maximum number of requests or actions allowed within a certain time frame",
-				}
-				json.NewEncoder(w).Encode(response)
+			SetLimitHeaders(w.Header(), decision)
+
+			if !decision.Allowed() {
+				WriteTooManyRequests(w)
 				return
 			}
 
@@ -44,26 +73,56 @@ func RateLimiter(rl *limiter.RateLimiter) func(http.Handler) http.Handler {
 	}
 }
 
-// getIP extrai o IP real da requisição
-func getIP(r *http.Request) string {
-	// Tenta X-Forwarded-For primeiro (para proxies/load balancers)
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		return strings.TrimSpace(ips[0])
+// WriteTooManyRequests escreve a resposta 429 padrão do rate limiter
+func WriteTooManyRequests(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	response := dto.ResponseMessage{
+		Message: LimitExceededMessage,
 	}
+	json.NewEncoder(w).Encode(response)
+}
 
-	// Tenta X-Real-IP
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
+// SetLimitHeaders informa ao cliente o limite e quanto resta na janela; no modo
+// proxy os headers do upstream são acrescentados a estes
+func SetLimitHeaders(h http.Header, d limiter.Decision) {
+	switch d.Outcome {
+	case limiter.OutcomeAllowed, limiter.OutcomeDenied:
+		h.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
 	}
 
-	// Usa RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	// só a requisição que causou o bloqueio sabe quando ele termina
+	if d.Outcome == limiter.OutcomeDenied {
+		h.Set("Retry-After", strconv.Itoa(int(math.Ceil(d.BlockDuration.Seconds()))))
+	}
+}
+
+// endDecisionSpan registra o resultado da decisão no span; o IP/token não é
+// registrado para não vazar credenciais nos traces
+func endDecisionSpan(span trace.Span, d limiter.Decision, err error) {
+	span.SetAttributes(
+		attribute.String("ratelimiter.type", d.Kind),
+		attribute.String("ratelimiter.policy", d.Policy),
+		attribute.String("ratelimiter.outcome", string(d.Outcome)),
+		attribute.Int("ratelimiter.limit", d.Limit),
+		attribute.Int("ratelimiter.remaining", d.Remaining),
+	)
 	if err != nil {
-		return r.RemoteAddr
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	span.End()
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
 
-	return ip
+// ClientIP retorna o IP da conexão, sem ler headers; atrás de proxies use
+// TrustedProxies.ClientIP (WithTrustedProxies no middleware)
+func ClientIP(r *http.Request) string {
+	return TrustedProxies(nil).ClientIP(r)
 }
@@ -6,10 +6,13 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/alexduzi/labratelimiter/internal/clock"
 	"github.com/alexduzi/labratelimiter/internal/config"
 	"github.com/alexduzi/labratelimiter/internal/dto"
 	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
 	"github.com/alexduzi/labratelimiter/internal/storage"
 )
 
@@ -58,6 +61,12 @@ func setupRouter(t *testing.T) *http.ServeMux {
 func setupServer(t *testing.T) (*httptest.Server, *http.Client) {
 	t.Helper()
 
+	return setupServerWithClock(t, clock.Real())
+}
+
+func setupServerWithClock(t *testing.T, clk clock.Clock) (*httptest.Server, *http.Client) {
+	t.Helper()
+
 	setEnvs(t)
 	t.Cleanup(func() { unsetEnvs(t) })
 
@@ -66,8 +75,10 @@ func setupServer(t *testing.T) (*httptest.Server, *http.Client) {
 		t.Fatalf("failed to load config: %v", err)
 	}
 
-	store := storage.NewMemoryStorage()
-	rl := limiter.NewRateLimiter(store, cfg)
+	store := storage.NewMemoryStorage(storage.WithMemoryClock(clk))
+	t.Cleanup(func() { store.Close() })
+
+	rl := limiterconfig.New(store, cfg, limiter.WithClock(clk))
 	mux := setupRouter(t)
 	handler := RateLimiter(rl)(mux)
 
@@ -342,3 +353,170 @@ func TestRateLimiterMiddleware_HealthEndpointAlsoRateLimited(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, resp.StatusCode)
 	}
 }
+
+func doRequest(t *testing.T, client *http.Client, url, token string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("API_KEY", token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestRateLimiterMiddleware_SetsLimitHeaders(t *testing.T) {
+	server, client := setupServer(t)
+
+	tests := []struct {
+		remaining  string
+		retryAfter string
+	}{
+		{"2", ""},
+		{"1", ""},
+		{"0", ""},
+		{"0", "3"},
+	}
+
+	for i, tt := range tests {
+		resp, err := client.Get(server.URL + "/")
+		if err != nil {
+			t.Fatalf("request %d: failed to execute request: %v", i+1, err)
+		}
+		resp.Body.Close()
+
+		if got := resp.Header.Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("request %d: expected X-RateLimit-Limit 3, got %q", i+1, got)
+		}
+		if got := resp.Header.Get("X-RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("request %d: expected X-RateLimit-Remaining %s, got %q", i+1, tt.remaining, got)
+		}
+		if got := resp.Header.Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("request %d: expected Retry-After %q, got %q", i+1, tt.retryAfter, got)
+		}
+	}
+}
+
+func TestRateLimiterMiddleware_IPIsReleasedAfterBlockDuration(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	server, client := setupServerWithClock(t, clk)
+
+	for i := 1; i <= 3; i++ {
+		if status := doRequest(t, client, server.URL+"/", ""); status != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, status)
+		}
+	}
+
+	if status := doRequest(t, client, server.URL+"/", ""); status != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d after exceeding the limit, got %d", http.StatusTooManyRequests, status)
+	}
+
+	// IP_BLOCK_DURATION=3s: ainda bloqueado logo antes do fim
+	clk.Advance(3*time.Second - time.Millisecond)
+	if status := doRequest(t, client, server.URL+"/", ""); status != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d before the block expires, got %d", http.StatusTooManyRequests, status)
+	}
+
+	clk.Advance(time.Millisecond)
+	if status := doRequest(t, client, server.URL+"/", ""); status != http.StatusOK {
+		t.Errorf("expected status %d after the block expires, got %d", http.StatusOK, status)
+	}
+}
+
+func TestRateLimiterMiddleware_TokenIsReleasedAfterBlockDuration(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	server, client := setupServerWithClock(t, clk)
+
+	for i := 1; i <= 5; i++ {
+		doRequest(t, client, server.URL+"/", "token-a")
+	}
+
+	clk.Advance(3 * time.Second)
+	if status := doRequest(t, client, server.URL+"/", "token-a"); status != http.StatusTooManyRequests {
+		t.Fatalf("expected token to stay blocked for TOKEN_BLOCK_DURATION=4s, got %d", status)
+	}
+
+	clk.Advance(time.Second)
+	if status := doRequest(t, client, server.URL+"/", "token-a"); status != http.StatusOK {
+		t.Errorf("expected status %d after the block expires, got %d", http.StatusOK, status)
+	}
+}
+
+func TestRateLimiterMiddleware_CounterResetsEachWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	server, client := setupServerWithClock(t, clk)
+
+	for window := 1; window <= 3; window++ {
+		for i := 1; i <= 3; i++ {
+			if status := doRequest(t, client, server.URL+"/", ""); status != http.StatusOK {
+				t.Fatalf("window %d, request %d: expected status %d, got %d", window, i, http.StatusOK, status)
+			}
+		}
+		clk.Advance(time.Second)
+	}
+}
+
+func TestRateLimiterMiddleware_RoutePolicy(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{IpLimitRps: 3, IpBlockDuration: time.Minute, TokenLimitRps: 4, TokenBlockDuration: time.Minute, KeyPrefix: "test"}
+	rl := limiterconfig.New(store, cfg,
+		limiter.WithPolicy(limiter.Policy{Name: "orders", Limit: 1, Window: time.Minute, BlockDuration: time.Minute}))
+
+	mux := setupRouter(t)
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	server := httptest.NewServer(RateLimiter(rl, WithRoutePolicy("GET /orders/{id}", "orders"))(mux))
+	t.Cleanup(server.Close)
+
+	if code := doRequest(t, server.Client(), server.URL+"/orders/1", ""); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := doRequest(t, server.Client(), server.URL+"/orders/2", ""); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 from the route policy, got %d", code)
+	}
+
+	// a política da rota não consome o limite por IP
+	for i := 0; i < 3; i++ {
+		if code := doRequest(t, server.Client(), server.URL+"/health", ""); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, code)
+		}
+	}
+}
+
+func TestRateLimiterMiddleware_RoutePolicyKeepsTokensAndIPsApart(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	cfg := &config.Config{IpLimitRps: 3, IpBlockDuration: time.Minute, TokenLimitRps: 4, TokenBlockDuration: time.Minute, KeyPrefix: "test"}
+	rl := limiterconfig.New(store, cfg,
+		limiter.WithPolicy(limiter.Policy{Name: "orders", Limit: 1, Window: time.Minute, BlockDuration: time.Minute}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	server := httptest.NewServer(RateLimiter(rl, WithRoutePolicy("GET /orders/{id}", "orders"))(mux))
+	t.Cleanup(server.Close)
+
+	if code := doRequest(t, server.Client(), server.URL+"/orders/1", ""); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := doRequest(t, server.Client(), server.URL+"/orders/1", ""); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for the client IP, got %d", code)
+	}
+
+	// um token igual ao IP bloqueado tem o seu próprio limite
+	if code := doRequest(t, server.Client(), server.URL+"/orders/1", "127.0.0.1"); code != http.StatusOK {
+		t.Errorf("expected token equal to the blocked IP to be allowed, got %d", code)
+	}
+}
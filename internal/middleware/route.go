@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/requestinfo"
+	"go.opentelemetry.io/otel"
+)
+
+// Request são os dados que o middleware HTTP e os adapters de frameworks
+// extraem da requisição, cada um com o seu próprio contexto
+type Request struct {
+	// ID vem do X-Request-ID; vazio gera um novo
+	ID     string
+	Method string
+	Path   string
+	// Route é o template da rota no framework (ex.: "/users/{id}" no chi,
+	// "/users/:id" no gin); vazio quando não há rota casada
+	Route string
+	IP    string
+	Token string
+	// Policy e ClientID, quando informados, substituem a política da rota e a
+	// chave API_KEY/IP (ex.: GRPCKeyFunc)
+	Policy   string
+	ClientID string
+}
+
+// RouteLimiter é a decisão compartilhada pelo middleware HTTP e pelos adapters.
+// Rotas com política (WithRoutePolicy) usam essa política com a chave API_KEY
+// ou IP; as demais seguem a regra padrão de Decide.
+type RouteLimiter struct {
+	rl      *limiter.RateLimiter
+	routes  map[string]string
+	trusted TrustedProxies
+}
+
+type RouteOption func(*RouteLimiter)
+
+// WithRoutePolicy aplica a política às requisições da rota, identificada pelo
+// template do framework
+func WithRoutePolicy(route, policy string) RouteOption {
+	return func(l *RouteLimiter) {
+		l.routes[route] = policy
+	}
+}
+
+// WithTrustedProxies faz o middleware HTTP ler o IP do cliente de
+// X-Forwarded-For/X-Real-IP quando a conexão vem de um desses proxies
+func WithTrustedProxies(trusted TrustedProxies) RouteOption {
+	return func(l *RouteLimiter) {
+		l.trusted = trusted
+	}
+}
+
+func NewRouteLimiter(rl *limiter.RateLimiter, opts ...RouteOption) *RouteLimiter {
+	l := &RouteLimiter{rl: rl, routes: make(map[string]string)}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Decide registra os dados da requisição no contexto (para auditoria e eventos)
+// e retorna o contexto, o ID da requisição e a decisão
+func (l *RouteLimiter) Decide(ctx context.Context, req Request) (context.Context, string, limiter.Decision, error) {
+	requestID := req.ID
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+
+	ctx = requestinfo.NewContext(ctx, requestinfo.Info{
+		RequestID: requestID,
+		Method:    req.Method,
+		Route:     req.Path,
+	})
+	ctx, span := otel.Tracer(limiter.TracerName).Start(ctx, "ratelimiter.decision")
+
+	var (
+		decision limiter.Decision
+		err      error
+	)
+	if req.Policy != "" {
+		decision, err = l.rl.DecidePolicy(ctx, req.Policy, req.ClientID, 1)
+	} else if policy, ok := l.routes[req.Route]; ok && req.Route != "" {
+		decision, err = l.rl.DecidePolicy(ctx, policy, limiter.ClientID(req.Token, req.IP), 1)
+	} else {
+		decision, err = l.rl.Decide(ctx, req.IP, req.Token)
+	}
+	endDecisionSpan(span, decision, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "rate limiter failed", slog.String("request_id", requestID), slog.Any("error", err))
+	}
+
+	return ctx, requestID, decision, err
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies são as redes dos proxies e load balancers cujos headers de IP
+// (X-Forwarded-For, X-Real-IP) são confiáveis. Sem proxies confiáveis o IP é
+// sempre o da conexão, já que qualquer cliente pode enviar esses headers.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies interpreta IPs e CIDRs, ex.: "10.0.0.0/8", "192.168.1.10"
+func ParseTrustedProxies(specs []string) (TrustedProxies, error) {
+	trusted := make(TrustedProxies, 0, len(specs))
+	for _, spec := range specs {
+		if prefix, err := netip.ParsePrefix(spec); err == nil {
+			trusted = append(trusted, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q, expected an IP or CIDR", spec)
+		}
+		addr = addr.Unmap()
+		trusted = append(trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return trusted, nil
+}
+
+func (t TrustedProxies) contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Trusts informa se a conexão da requisição vem de um proxy confiável
+func (t TrustedProxies) Trusts(r *http.Request) bool {
+	return t.contains(hostIP(r.RemoteAddr))
+}
+
+// ClientIP retorna o IP do cliente da requisição; os headers só são lidos
+// quando a conexão vem de um proxy confiável
+func (t TrustedProxies) ClientIP(r *http.Request) string {
+	return t.clientIP(hostIP(r.RemoteAddr), r.Header.Values("X-Forwarded-For"), r.Header.Get("X-Real-IP"))
+}
+
+// clientIP percorre o X-Forwarded-For da direita para a esquerda, pulando os
+// proxies confiáveis: o primeiro endereço que não é de um deles é o cliente.
+// O lado esquerdo da lista é escrito pelo cliente e nunca é usado diretamente.
+func (t TrustedProxies) clientIP(peer string, forwardedFor []string, realIP string) string {
+	if !t.contains(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		for hop := range strings.SplitSeq(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !t.contains(hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		return hops[0]
+	}
+
+	if realIP = strings.TrimSpace(realIP); realIP != "" {
+		return realIP
+	}
+	return peer
+}
+
+func hostIP(addr string) string {
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return ip
+}
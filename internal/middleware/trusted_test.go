@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		trusted   TrustedProxies
+		remote    string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{"no trusted proxies ignores headers", nil, "10.0.0.1:1234", "203.0.113.7", "203.0.113.8", "10.0.0.1"},
+		{"untrusted peer ignores headers", trusted, "198.51.100.1:1234", "203.0.113.7", "", "198.51.100.1"},
+		{"trusted peer", trusted, "10.0.0.1:1234", "203.0.113.7", "", "203.0.113.7"},
+		{"spoofed left side is skipped", trusted, "10.0.0.1:1234", "1.1.1.1, 203.0.113.7", "", "203.0.113.7"},
+		{"chain of trusted proxies", trusted, "10.0.0.1:1234", "203.0.113.7, 192.168.1.10, 10.0.0.2", "", "203.0.113.7"},
+		{"real ip from trusted peer", trusted, "192.168.1.10:1234", "", "203.0.113.9", "203.0.113.9"},
+		{"trusted peer without headers", trusted, "10.0.0.1:1234", "", "", "10.0.0.1"},
+		{"ipv6 peer", trusted, "[2001:db8::1]:1234", "203.0.113.7", "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := tt.trusted.ClientIP(req); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies_RejectsInvalid(t *testing.T) {
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected error for an invalid CIDR")
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("expected error for a hostname")
+	}
+}
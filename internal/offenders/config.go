@@ -0,0 +1,26 @@
+package offenders
+
+import (
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// New cria o tracker de acordo com OFFENDERS_MODE; retorna nil com "off". O modo
+// redis usa a mesma instância configurada para o storage.
+func New(cfg *config.Config, prefix string) Tracker {
+	opts := []Option{WithWindow(cfg.OffendersWindow), WithCapacity(cfg.OffendersCapacity)}
+
+	switch cfg.OffendersMode {
+	case "local":
+		return NewLocal(opts...)
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		return NewRedis(client, prefix, cfg.OffendersSyncInterval, opts...)
+	default:
+		return nil
+	}
+}
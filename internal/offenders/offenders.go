@@ -0,0 +1,275 @@
+// Package offenders estima os IPs e tokens que mais consomem quota ou mais são
+// negados, sem guardar todas as chaves: cada minuto tem um Count-Min Sketch e
+// uma lista top-K, e a consulta soma os últimos minutos da janela.
+package offenders
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+)
+
+type Metric string
+
+const (
+	// MetricRequests conta as requisições que consumiram quota (permitidas ou que estouraram o limite)
+	MetricRequests Metric = "requests"
+	// MetricDenials conta as requisições negadas, inclusive as de chaves já bloqueadas
+	MetricDenials Metric = "denials"
+)
+
+var Metrics = []Metric{MetricRequests, MetricDenials}
+
+// ParseMetric valida o nome de uma métrica vindo da API
+func ParseMetric(s string) (Metric, error) {
+	for _, m := range Metrics {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown metric %q, expected requests or denials", s)
+}
+
+const (
+	bucketSize = time.Minute
+
+	DefaultWindow   = 5 * time.Minute
+	DefaultCapacity = 100
+	DefaultTop      = 20
+
+	sketchWidth = 2048
+	sketchDepth = 4
+)
+
+// Entry é uma chave do top. Kind é a política do contador (como em
+// RateLimiter.State), para que a entrada possa ser desbloqueada pela API.
+type Entry struct {
+	Kind  string
+	ID    string
+	Count uint64
+}
+
+// Tracker recebe as decisões do limiter e responde o top N da janela
+type Tracker interface {
+	limiter.Observer
+	Top(ctx context.Context, m Metric, n int) ([]Entry, error)
+	Window() time.Duration
+	// Close libera os recursos do tracker; pode ser chamado mais de uma vez
+	Close(ctx context.Context) error
+}
+
+type options struct {
+	window   time.Duration
+	capacity int
+	clock    clock.Clock
+}
+
+type Option func(*options)
+
+// WithWindow define a janela da consulta, arredondada para minutos inteiros
+func WithWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.window = window
+	}
+}
+
+// WithCapacity define quantas chaves candidatas cada minuto guarda; limita o N das consultas
+func WithCapacity(capacity int) Option {
+	return func(o *options) {
+		o.capacity = capacity
+	}
+}
+
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// Local mantém as estatísticas apenas desta instância
+type Local struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	capacity int
+	window   time.Duration
+	buckets  map[Metric][]*bucket
+}
+
+type bucket struct {
+	epoch  int64
+	sketch *countMinSketch
+	top    *topK
+}
+
+func NewLocal(opts ...Option) *Local {
+	o := options{
+		window:   DefaultWindow,
+		capacity: DefaultCapacity,
+		clock:    clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	n := max(int((o.window+bucketSize-1)/bucketSize), 1)
+
+	l := &Local{
+		clock:    o.clock,
+		capacity: o.capacity,
+		window:   time.Duration(n) * bucketSize,
+		buckets:  make(map[Metric][]*bucket, len(Metrics)),
+	}
+	for _, m := range Metrics {
+		buckets := make([]*bucket, n)
+		for i := range buckets {
+			buckets[i] = &bucket{
+				epoch:  -1,
+				sketch: newCountMinSketch(sketchWidth, sketchDepth),
+				top:    newTopK(o.capacity),
+			}
+		}
+		l.buckets[m] = buckets
+	}
+
+	return l
+}
+
+func (l *Local) Window() time.Duration {
+	return l.window
+}
+
+func (l *Local) ObserveDecision(_ context.Context, d limiter.Decision) {
+	var metrics []Metric
+	switch d.Outcome {
+	case limiter.OutcomeAllowed:
+		metrics = []Metric{MetricRequests}
+	case limiter.OutcomeDenied:
+		metrics = []Metric{MetricRequests, MetricDenials}
+	case limiter.OutcomeBlocked:
+		metrics = []Metric{MetricDenials}
+	default:
+		return
+	}
+
+	key := entryKey(d.Policy, d.ID)
+	epoch := l.epoch()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, m := range metrics {
+		b := l.bucket(m, epoch)
+		b.top.offer(key, b.sketch.add(key, uint64(d.Cost)))
+	}
+}
+
+func (l *Local) Top(_ context.Context, m Metric, n int) ([]Entry, error) {
+	epoch := l.epoch()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buckets, ok := l.buckets[m]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", m)
+	}
+
+	var live []*bucket
+	for _, b := range buckets {
+		if b.epoch > epoch-int64(len(buckets)) {
+			live = append(live, b)
+		}
+	}
+
+	// um candidato pode estar no top de um minuto e não de outro; a contagem
+	// final soma a estimativa de todos os minutos
+	counts := make(map[string]uint64)
+	for _, b := range live {
+		for _, key := range b.top.keys() {
+			if _, seen := counts[key]; seen {
+				continue
+			}
+			var total uint64
+			for _, other := range live {
+				total += other.sketch.estimate(key)
+			}
+			counts[key] = total
+		}
+	}
+
+	return topEntries(counts, n), nil
+}
+
+// Close não faz nada: o modo local não tem goroutines nem conexões
+func (l *Local) Close(context.Context) error {
+	return nil
+}
+
+// snapshot retorna os candidatos de um minuto com suas estimativas; usado pelo modo Redis
+func (l *Local) snapshot(m Metric, epoch int64) map[string]uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buckets := l.buckets[m]
+	b := buckets[epoch%int64(len(buckets))]
+	if b.epoch != epoch {
+		return nil
+	}
+
+	counts := make(map[string]uint64, len(b.top.items))
+	for _, key := range b.top.keys() {
+		counts[key] = b.sketch.estimate(key)
+	}
+	return counts
+}
+
+func (l *Local) epoch() int64 {
+	return l.clock.Now().Unix() / int64(bucketSize/time.Second)
+}
+
+// bucket retorna o balde do minuto, reaproveitando o mais antigo do anel
+func (l *Local) bucket(m Metric, epoch int64) *bucket {
+	buckets := l.buckets[m]
+	b := buckets[epoch%int64(len(buckets))]
+	if b.epoch != epoch {
+		b.epoch = epoch
+		b.sketch.reset()
+		b.top.reset()
+	}
+	return b
+}
+
+func entryKey(kind, id string) string {
+	return kind + ":" + id
+}
+
+func parseEntryKey(key string) (kind, id string) {
+	kind, id, _ = strings.Cut(key, ":")
+	return kind, id
+}
+
+func topEntries(counts map[string]uint64, n int) []Entry {
+	entries := make([]Entry, 0, len(counts))
+	for key, count := range counts {
+		kind, id := parseEntryKey(key)
+		entries = append(entries, Entry{Kind: kind, ID: id, Count: count})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entryKey(entries[i].Kind, entries[i].ID) < entryKey(entries[j].Kind, entries[j].ID)
+	})
+
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
@@ -0,0 +1,146 @@
+package offenders
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+)
+
+func observe(t *Local, kind, id string, outcome limiter.Outcome, n int) {
+	for i := 0; i < n; i++ {
+		t.ObserveDecision(context.Background(), limiter.Decision{Kind: kind, ID: id, Policy: kind, Outcome: outcome, Cost: 1})
+	}
+}
+
+func TestLocal_TopByMetric(t *testing.T) {
+	tracker := NewLocal(WithClock(clock.NewFake(time.Unix(1_700_000_000, 0))))
+
+	observe(tracker, limiter.KindIP, "10.0.0.1", limiter.OutcomeAllowed, 50)
+	observe(tracker, limiter.KindIP, "10.0.0.2", limiter.OutcomeDenied, 5)
+	observe(tracker, limiter.KindIP, "10.0.0.2", limiter.OutcomeBlocked, 20)
+	observe(tracker, limiter.KindToken, "abc", limiter.OutcomeDenied, 3)
+	observe(tracker, limiter.KindIP, "10.0.0.3", limiter.OutcomeFailOpen, 100)
+
+	tests := []struct {
+		metric Metric
+		want   []Entry
+	}{
+		{MetricRequests, []Entry{
+			{Kind: limiter.KindIP, ID: "10.0.0.1", Count: 50},
+			{Kind: limiter.KindIP, ID: "10.0.0.2", Count: 5},
+			{Kind: limiter.KindToken, ID: "abc", Count: 3},
+		}},
+		{MetricDenials, []Entry{
+			{Kind: limiter.KindIP, ID: "10.0.0.2", Count: 25},
+			{Kind: limiter.KindToken, ID: "abc", Count: 3},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.metric), func(t *testing.T) {
+			got, err := tracker.Top(context.Background(), tt.metric, 10)
+			if err != nil {
+				t.Fatalf("top: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLocal_CountsCost(t *testing.T) {
+	tracker := NewLocal(WithClock(clock.NewFake(time.Unix(1_700_000_000, 0))))
+
+	d := limiter.Decision{Kind: "export", ID: "user-1", Policy: "export", Outcome: limiter.OutcomeAllowed, Cost: 5}
+	tracker.ObserveDecision(context.Background(), d)
+	tracker.ObserveDecision(context.Background(), d)
+
+	got, err := tracker.Top(context.Background(), MetricRequests, 1)
+	if err != nil {
+		t.Fatalf("top: %v", err)
+	}
+	if len(got) != 1 || got[0].Count != 10 {
+		t.Errorf("expected user-1 with 10 requests, got %v", got)
+	}
+}
+
+func TestLocal_SlidingWindow(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	tracker := NewLocal(WithClock(clk), WithWindow(3*time.Minute))
+
+	observe(tracker, limiter.KindIP, "10.0.0.1", limiter.OutcomeDenied, 10)
+	clk.Advance(time.Minute)
+	observe(tracker, limiter.KindIP, "10.0.0.1", limiter.OutcomeDenied, 5)
+	observe(tracker, limiter.KindIP, "10.0.0.2", limiter.OutcomeDenied, 12)
+
+	top := func() []Entry {
+		entries, err := tracker.Top(context.Background(), MetricDenials, 1)
+		if err != nil {
+			t.Fatalf("top: %v", err)
+		}
+		return entries
+	}
+
+	// as contagens dos minutos são somadas
+	if got := top(); len(got) != 1 || got[0].ID != "10.0.0.1" || got[0].Count != 15 {
+		t.Fatalf("expected 10.0.0.1 with 15 denials, got %v", got)
+	}
+
+	// o primeiro minuto saiu da janela
+	clk.Advance(2 * time.Minute)
+	if got := top(); len(got) != 1 || got[0].ID != "10.0.0.2" || got[0].Count != 12 {
+		t.Fatalf("expected 10.0.0.2 with 12 denials, got %v", got)
+	}
+
+	clk.Advance(time.Minute)
+	if got := top(); len(got) != 0 {
+		t.Fatalf("expected empty top after the window, got %v", got)
+	}
+}
+
+func TestLocal_HeavyHittersAmongManyKeys(t *testing.T) {
+	tracker := NewLocal(WithClock(clock.NewFake(time.Unix(1_700_000_000, 0))), WithCapacity(20))
+
+	// muitas chaves com poucas negações intercaladas com alguns abusadores
+	for i := 0; i < 20_000; i++ {
+		observe(tracker, limiter.KindIP, fmt.Sprintf("192.168.%d.%d", i/256, i%256), limiter.OutcomeDenied, 1)
+		if i%100 == 0 {
+			for j := 0; j < 5; j++ {
+				observe(tracker, limiter.KindIP, fmt.Sprintf("203.0.113.%d", j), limiter.OutcomeDenied, j+1)
+			}
+		}
+	}
+
+	got, err := tracker.Top(context.Background(), MetricDenials, 5)
+	if err != nil {
+		t.Fatalf("top: %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected 5 entries, got %v", got)
+	}
+	for i, e := range got {
+		want := fmt.Sprintf("203.0.113.%d", 4-i)
+		exact := uint64(200 * (5 - i))
+		if e.ID != want {
+			t.Errorf("rank %d: expected %s, got %s", i+1, want, e.ID)
+		}
+		// o sketch só superestima, e pouco com essa carga
+		if e.Count < exact || e.Count > exact+exact/10 {
+			t.Errorf("rank %d: estimate %d too far from %d", i+1, e.Count, exact)
+		}
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	if m, err := ParseMetric("denials"); err != nil || m != MetricDenials {
+		t.Errorf("expected denials, got %q, %v", m, err)
+	}
+	if _, err := ParseMetric("bytes"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
@@ -0,0 +1,183 @@
+package offenders
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+const DefaultSyncInterval = 10 * time.Second
+
+// Redis agrega o top de todas as instâncias. Cada instância continua contando
+// localmente e, a cada intervalo, grava os candidatos dos minutos recentes em um
+// sorted set próprio (<prefix>offenders:<métrica>:<minuto>:<instância>); a
+// consulta soma os sets de todas as instâncias com ZUNION. O Redis guarda no
+// máximo capacity chaves por instância e minuto, e o resultado pode atrasar até
+// um intervalo em relação às instâncias.
+type Redis struct {
+	local    *Local
+	client   *redis.Client
+	prefix   string
+	instance string
+	interval time.Duration
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewRedis inicia a sincronização periódica; prefix normalmente é o prefixo das chaves do limiter
+func NewRedis(client *redis.Client, prefix string, interval time.Duration, opts ...Option) *Redis {
+	r := &Redis{
+		local:    NewLocal(opts...),
+		client:   client,
+		prefix:   prefix,
+		instance: instanceID(),
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go r.run()
+	return r
+}
+
+func (r *Redis) Window() time.Duration {
+	return r.local.Window()
+}
+
+func (r *Redis) ObserveDecision(ctx context.Context, d limiter.Decision) {
+	r.local.ObserveDecision(ctx, d)
+}
+
+func (r *Redis) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Sync(context.Background()); err != nil {
+				slog.Warn("failed to sync top offenders", slog.Any("error", err))
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Sync grava no Redis os candidatos do minuto atual e do anterior (que pode ter
+// mudado desde a última sincronização)
+func (r *Redis) Sync(ctx context.Context) error {
+	epoch := r.local.epoch()
+	ttl := r.local.Window() + bucketSize
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, m := range Metrics {
+			for _, e := range []int64{epoch - 1, epoch} {
+				counts := r.local.snapshot(m, e)
+				if len(counts) == 0 {
+					continue
+				}
+
+				index := r.indexKey(m, e)
+				key := index + ":" + r.instance
+
+				members := make([]redis.Z, 0, len(counts))
+				for member, count := range counts {
+					members = append(members, redis.Z{Score: float64(count), Member: member})
+				}
+
+				pipe.Del(ctx, key)
+				pipe.ZAdd(ctx, key, members...)
+				pipe.Expire(ctx, key, ttl)
+				pipe.SAdd(ctx, index, key)
+				pipe.Expire(ctx, index, ttl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync top offenders: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) Top(ctx context.Context, m Metric, n int) ([]Entry, error) {
+	if _, err := ParseMetric(string(m)); err != nil {
+		return nil, err
+	}
+
+	epoch := r.local.epoch()
+	buckets := int64(r.local.Window() / bucketSize)
+
+	pipe := r.client.Pipeline()
+	indexes := make([]*redis.StringSliceCmd, 0, buckets)
+	for e := epoch - buckets + 1; e <= epoch; e++ {
+		indexes = append(indexes, pipe.SMembers(ctx, r.indexKey(m, e)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read top offenders: %w", err)
+	}
+
+	var keys []string
+	for _, cmd := range indexes {
+		keys = append(keys, cmd.Val()...)
+	}
+	if len(keys) == 0 {
+		return []Entry{}, nil
+	}
+
+	members, err := r.client.ZUnionWithScores(ctx, redis.ZStore{Keys: keys, Aggregate: "SUM"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read top offenders: %w", err)
+	}
+
+	counts := make(map[string]uint64, len(members))
+	for _, z := range members {
+		if member, ok := z.Member.(string); ok {
+			counts[member] = uint64(z.Score)
+		}
+	}
+	return topEntries(counts, n), nil
+}
+
+// Close para a sincronização e grava os últimos dados antes de sair; chamadas
+// seguintes retornam o resultado da primeira
+func (r *Redis) Close(ctx context.Context) error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+
+		r.closeErr = errors.Join(r.Sync(ctx), r.client.Close())
+	})
+	return r.closeErr
+}
+
+func (r *Redis) indexKey(m Metric, epoch int64) string {
+	return r.prefix + "offenders:" + string(m) + ":" + strconv.FormatInt(epoch, 10)
+}
+
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
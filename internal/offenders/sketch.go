@@ -0,0 +1,135 @@
+package offenders
+
+import (
+	"container/heap"
+	"hash/maphash"
+	"math"
+)
+
+// countMinSketch estima a frequência de cada chave em memória fixa; a estimativa
+// nunca é menor que o valor real e o erro cresce com o total de eventos / width
+type countMinSketch struct {
+	// uma seed por linha: com double hashing e width potência de 2, duas chaves
+	// que colidem nos bits baixos dos dois hashes colidem em todas as linhas
+	seeds []maphash.Seed
+	width uint64
+	rows  [][]uint64
+}
+
+func newCountMinSketch(width, depth int) *countMinSketch {
+	seeds := make([]maphash.Seed, depth)
+	rows := make([][]uint64, depth)
+	for i := range rows {
+		seeds[i] = maphash.MakeSeed()
+		rows[i] = make([]uint64, width)
+	}
+	return &countMinSketch{seeds: seeds, width: uint64(width), rows: rows}
+}
+
+// add incrementa a chave e retorna a nova estimativa
+func (s *countMinSketch) add(key string, n uint64) uint64 {
+	estimate := uint64(math.MaxUint64)
+	for i, row := range s.rows {
+		idx := s.index(i, key)
+		row[idx] += n
+		estimate = min(estimate, row[idx])
+	}
+	return estimate
+}
+
+func (s *countMinSketch) estimate(key string) uint64 {
+	estimate := uint64(math.MaxUint64)
+	for i, row := range s.rows {
+		estimate = min(estimate, row[s.index(i, key)])
+	}
+	return estimate
+}
+
+func (s *countMinSketch) index(row int, key string) uint64 {
+	return maphash.String(s.seeds[row], key) % s.width
+}
+
+func (s *countMinSketch) reset() {
+	for _, row := range s.rows {
+		clear(row)
+	}
+}
+
+// topK mantém as capacity chaves com maior estimativa vistas até agora
+type topK struct {
+	capacity int
+	items    topHeap
+	index    map[string]*topItem
+}
+
+type topItem struct {
+	key   string
+	count uint64
+	pos   int
+}
+
+func newTopK(capacity int) *topK {
+	return &topK{capacity: capacity, index: make(map[string]*topItem, capacity)}
+}
+
+// offer atualiza a chave com a estimativa atual, substituindo o menor item se
+// a lista estiver cheia e a chave tiver passado dele
+func (t *topK) offer(key string, count uint64) {
+	if item, ok := t.index[key]; ok {
+		item.count = count
+		heap.Fix(&t.items, item.pos)
+		return
+	}
+
+	if len(t.items) < t.capacity {
+		item := &topItem{key: key, count: count}
+		heap.Push(&t.items, item)
+		t.index[key] = item
+		return
+	}
+
+	if smallest := t.items[0]; count > smallest.count {
+		delete(t.index, smallest.key)
+		smallest.key, smallest.count = key, count
+		heap.Fix(&t.items, 0)
+		t.index[key] = smallest
+	}
+}
+
+func (t *topK) keys() []string {
+	keys := make([]string, 0, len(t.items))
+	for _, item := range t.items {
+		keys = append(keys, item.key)
+	}
+	return keys
+}
+
+func (t *topK) reset() {
+	t.items = t.items[:0]
+	clear(t.index)
+}
+
+// topHeap é um min-heap por contagem, para achar rápido o item a substituir
+type topHeap []*topItem
+
+func (h topHeap) Len() int           { return len(h) }
+func (h topHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h topHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *topHeap) Push(x any) {
+	item := x.(*topItem)
+	item.pos = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *topHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,177 @@
+// Package proxy implementa o modo reverse proxy: o servidor aplica o rate limit
+// e encaminha as requisições permitidas para um ou mais upstreams, escolhidos
+// pelo prefixo do caminho.
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+)
+
+// Route encaminha os caminhos que começam com Prefix para Target
+type Route struct {
+	Prefix string
+	Target *url.URL
+}
+
+type Proxy struct {
+	routes []route
+}
+
+type route struct {
+	prefix string
+	proxy  *httputil.ReverseProxy
+}
+
+// New cria o proxy; rotas com prefixo mais longo têm precedência e o prefixo
+// "/" funciona como upstream padrão. timeout limita a espera pelos headers da
+// resposta do upstream (0 desativa). O X-Forwarded-For recebido só é repassado
+// quando vem de um dos proxies confiáveis.
+func New(routes []Route, timeout time.Duration, trusted middleware.TrustedProxies) (*Proxy, error) {
+	if len(routes) == 0 {
+		return nil, errors.New("proxy: at least one upstream is required")
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	p := &Proxy{}
+	for _, r := range routes {
+		if r.Target == nil || r.Target.Scheme == "" || r.Target.Host == "" {
+			return nil, fmt.Errorf("proxy: invalid upstream for prefix %q", r.Prefix)
+		}
+
+		p.routes = append(p.routes, route{
+			prefix: "/" + strings.Trim(r.Prefix, "/"),
+			proxy:  newReverseProxy(r.Target, transport, trusted),
+		})
+	}
+
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		return len(p.routes[i].prefix) > len(p.routes[j].prefix)
+	})
+
+	return p, nil
+}
+
+func newReverseProxy(target *url.URL, transport http.RoundTripper, trusted middleware.TrustedProxies) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			// Rewrite remove os X-Forwarded-* recebidos; atrás de um proxy
+			// confiável mantém a cadeia anterior, e sempre acrescenta a conexão atual
+			if trusted.Trusts(r.In) {
+				r.Out.Header["X-Forwarded-For"] = r.In.Header["X-Forwarded-For"]
+			}
+			r.SetXForwarded()
+		},
+		Transport:    transport,
+		ErrorHandler: errorHandler,
+	}
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, rt := range p.routes {
+		if matches(r.URL.Path, rt.prefix) {
+			rt.proxy.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "no upstream configured for this path")
+}
+
+// matches verifica o prefixo respeitando os segmentos: /api casa com /api e
+// /api/x, mas não com /apix
+func matches(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || rest[0] == '/')
+}
+
+func errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	if errors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+		status = http.StatusGatewayTimeout
+	}
+
+	if errors.Is(err, context.Canceled) {
+		// o cliente desistiu; não há para quem responder
+		return
+	}
+
+	slog.WarnContext(r.Context(), "upstream request failed",
+		slog.String("host", r.URL.Host),
+		slog.Int("status", status),
+		slog.Any("error", err),
+	)
+	writeError(w, status, http.StatusText(status))
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(dto.ResponseMessage{Message: message})
+}
+
+// ParseRoutes interpreta o upstream padrão e a lista "prefixo=url" de rotas
+func ParseRoutes(defaultURL string, routes []string) ([]Route, error) {
+	var parsed []Route
+
+	add := func(prefix, raw string) error {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid upstream url %q", raw)
+		}
+		parsed = append(parsed, Route{Prefix: prefix, Target: u})
+		return nil
+	}
+
+	for _, r := range routes {
+		prefix, raw, ok := strings.Cut(r, "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("UPSTREAM_ROUTES: invalid route %q, expected /prefix=url", r)
+		}
+		if err := add(prefix, raw); err != nil {
+			return nil, fmt.Errorf("UPSTREAM_ROUTES: %w", err)
+		}
+	}
+
+	if defaultURL != "" {
+		if err := add("/", defaultURL); err != nil {
+			return nil, fmt.Errorf("UPSTREAM_URL: %w", err)
+		}
+	}
+
+	return parsed, nil
+}
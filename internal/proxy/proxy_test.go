@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+// upstream responde com o nome do serviço e os headers recebidos
+func upstream(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", name)
+		json.NewEncoder(w).Encode(map[string]string{
+			"path":              r.URL.Path,
+			"x_forwarded_for":   r.Header.Get("X-Forwarded-For"),
+			"x_forwarded_host":  r.Header.Get("X-Forwarded-Host"),
+			"x_forwarded_proto": r.Header.Get("X-Forwarded-Proto"),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func mustRoutes(t *testing.T, defaultURL string, routes ...string) []Route {
+	t.Helper()
+
+	parsed, err := ParseRoutes(defaultURL, routes)
+	if err != nil {
+		t.Fatalf("parse routes: %v", err)
+	}
+	return parsed
+}
+
+func TestProxy_RoutesByLongestPrefix(t *testing.T) {
+	users, orders, fallback := upstream(t, "users"), upstream(t, "orders"), upstream(t, "default")
+
+	p, err := New(mustRoutes(t, fallback.URL, "/api="+users.URL, "/api/orders="+orders.URL), time.Second, nil)
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/users/1", "users"},
+		{"/api", "users"},
+		{"/api/orders", "orders"},
+		{"/api/orders/42", "orders"},
+		{"/api/ordersx", "users"},
+		{"/apix", "default"},
+		{"/", "default"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if got := rec.Header().Get("X-Upstream"); got != tt.want {
+			t.Errorf("%s: expected upstream %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestProxy_WithoutDefaultReturnsNotFound(t *testing.T) {
+	users := upstream(t, "users")
+
+	p, err := New(mustRoutes(t, "", "/api="+users.URL), time.Second, nil)
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestProxy_RateLimitsAndForwardsHeaders(t *testing.T) {
+	backend := upstream(t, "default")
+
+	// o cliente de teste faz o papel de um load balancer confiável
+	trusted, err := middleware.ParseTrustedProxies([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("parse trusted proxies: %v", err)
+	}
+
+	p, err := New(mustRoutes(t, backend.URL), time.Second, trusted)
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+
+	cfg := &config.Config{IpLimitRps: 1, IpBlockDuration: time.Minute, TokenLimitRps: 1, TokenBlockDuration: time.Minute}
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	server := httptest.NewServer(middleware.RateLimiter(limiterconfig.New(store, cfg), middleware.WithTrustedProxies(trusted))(p))
+	t.Cleanup(server.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("X-RateLimit-Limit") != "1" || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("expected limit headers on proxied response, got %v", resp.Header)
+	}
+
+	var got map[string]string
+	json.NewDecoder(resp.Body).Decode(&got)
+
+	serverURL, _ := url.Parse(server.URL)
+	if got["path"] != "/orders" {
+		t.Errorf("expected path /orders upstream, got %q", got["path"])
+	}
+	if got["x_forwarded_for"] != "203.0.113.7, 127.0.0.1" {
+		t.Errorf("expected forwarded chain to be preserved, got %q", got["x_forwarded_for"])
+	}
+	if got["x_forwarded_host"] != serverURL.Host || got["x_forwarded_proto"] != "http" {
+		t.Errorf("unexpected X-Forwarded-Host/Proto: %v", got)
+	}
+
+	// segunda requisição do mesmo IP é bloqueada antes de chegar ao upstream
+	resp2, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusTooManyRequests || resp2.Header.Get("X-Upstream") != "" {
+		t.Errorf("expected 429 from the limiter, got %d (upstream %q)", resp2.StatusCode, resp2.Header.Get("X-Upstream"))
+	}
+}
+
+func TestProxy_DropsForwardedForFromUntrustedClients(t *testing.T) {
+	backend := upstream(t, "default")
+
+	p, err := New(mustRoutes(t, backend.URL), time.Second, nil)
+	if err != nil {
+		t.Fatalf("new proxy: %v", err)
+	}
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got map[string]string
+	json.NewDecoder(resp.Body).Decode(&got)
+	if got["x_forwarded_for"] != "127.0.0.1" {
+		t.Errorf("expected only the connection address upstream, got %q", got["x_forwarded_for"])
+	}
+}
+
+func TestProxy_UpstreamErrors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	t.Cleanup(slow.Close)
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name   string
+		target string
+		want   int
+	}{
+		{"timeout", slow.URL, http.StatusGatewayTimeout},
+		{"unreachable", down.URL, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(mustRoutes(t, tt.target), 50*time.Millisecond, nil)
+			if err != nil {
+				t.Fatalf("new proxy: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestParseRoutes_RejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		defaultURL string
+		routes     []string
+	}{
+		{"relative url", "localhost:8080", nil},
+		{"route without prefix", "", []string{"api=http://users"}},
+		{"route without url", "", []string{"/api"}},
+		{"unsupported scheme", "", []string{"/api=ftp://users"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRoutes(tt.defaultURL, tt.routes); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
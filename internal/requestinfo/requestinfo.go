@@ -0,0 +1,22 @@
+// Package requestinfo carrega no contexto os dados da requisição HTTP que
+// originou uma decisão, para logs de auditoria e eventos.
+package requestinfo
+
+import "context"
+
+type Info struct {
+	RequestID string
+	Method    string
+	Route     string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+func FromContext(ctx context.Context) (Info, bool) {
+	info, ok := ctx.Value(contextKey{}).(Info)
+	return info, ok
+}
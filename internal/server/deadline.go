@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Deadline é o prazo único do encerramento. O relógio começa na primeira chamada
+// de Context e o mesmo prazo vale para todas as etapas (servidores, eventos,
+// storage...), então o shutdown inteiro nunca passa de timeout.
+type Deadline struct {
+	timeout time.Duration
+
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewDeadline(timeout time.Duration) *Deadline {
+	return &Deadline{timeout: timeout}
+}
+
+// Context retorna o contexto do encerramento, iniciando o prazo se preciso
+func (d *Deadline) Context() context.Context {
+	d.once.Do(func() {
+		d.ctx, d.cancel = context.WithTimeout(context.Background(), d.timeout)
+	})
+	return d.ctx
+}
+
+// Cancel libera o timer; deve ser chamado depois da última etapa
+func (d *Deadline) Cancel() {
+	d.Context()
+	d.cancel()
+}
+
+// Sleep espera wait dentro do prazo, retornando antes se o prazo acabar
+func (d *Deadline) Sleep(wait time.Duration) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-d.Context().Done():
+	}
+}
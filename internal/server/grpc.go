@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// GRPCServer adapta um *grpc.Server à interface Server
+type GRPCServer struct {
+	Addr   string
+	Server *grpc.Server
+}
+
+func NewGRPC(addr string, srv *grpc.Server) *GRPCServer {
+	return &GRPCServer{Addr: addr, Server: srv}
+}
+
+func (g *GRPCServer) ListenAndServe() error {
+	lis, err := net.Listen("tcp", g.Addr)
+	if err != nil {
+		return err
+	}
+
+	if err := g.Server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown espera as chamadas em andamento terminarem; se ctx expirar antes,
+// as conexões são fechadas
+func (g *GRPCServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.Server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.Server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (g *GRPCServer) Close() error {
+	g.Server.Stop()
+	return nil
+}
@@ -0,0 +1,81 @@
+// Package server configura os http.Server da aplicação e coordena o shutdown
+// gracioso: ao cancelar o contexto, param de aceitar conexões e esperam as
+// requisições em andamento terminarem.
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+)
+
+// New cria um http.Server com os timeouts e o limite de headers da configuração
+func New(addr string, handler http.Handler, cfg *config.Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+		MaxHeaderBytes:    cfg.ServerMaxHeaderBytes,
+	}
+}
+
+// Server é implementado por *http.Server e por GRPCServer
+type Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// Serve roda os servidores até ctx ser cancelado ou algum deles falhar, e então
+// faz o shutdown de todos, esperando as requisições em andamento até o fim de
+// deadline. Retorna os erros de ListenAndServe e do shutdown.
+func Serve(ctx context.Context, deadline *Deadline, servers ...Server) error {
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			slog.Info("Server listening", slog.String("addr", addrOf(srv)))
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+				return
+			}
+			errCh <- nil
+		}()
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down, draining in-flight requests")
+	case serveErr = <-errCh:
+		slog.Error("Server failed, shutting down", slog.Any("error", serveErr))
+	}
+
+	sctx := deadline.Context()
+
+	errs := []error{serveErr}
+	for _, srv := range servers {
+		if err := srv.Shutdown(sctx); err != nil {
+			errs = append(errs, err)
+			srv.Close()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func addrOf(srv Server) string {
+	switch s := srv.(type) {
+	case *http.Server:
+		return s.Addr
+	case *GRPCServer:
+		return s.Addr
+	default:
+		return ""
+	}
+}
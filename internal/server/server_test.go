@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		ServerReadTimeout:       time.Second,
+		ServerReadHeaderTimeout: time.Second,
+		ServerWriteTimeout:      5 * time.Second,
+		ServerIdleTimeout:       time.Second,
+		ServerMaxHeaderBytes:    1 << 20,
+	}
+}
+
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server did not start listening on %s", addr)
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	addr := freeAddr(t)
+	srv := New(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	}), testConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, NewDeadline(5*time.Second), srv) }()
+	waitListening(t, addr)
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	// o servidor deve esperar a requisição em andamento antes de retornar
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	if r := <-response; r.err != nil || r.body != "done" {
+		t.Fatalf("expected in-flight request to complete, got %q, %v", r.body, r.err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+
+	if _, err := http.Get("http://" + addr); err == nil {
+		t.Error("expected new connections to be refused after shutdown")
+	}
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+
+	addr := freeAddr(t)
+	srv := New(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}), testConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, NewDeadline(50*time.Millisecond), srv) }()
+	waitListening(t, addr)
+
+	go http.Get("http://" + addr)
+	<-started
+	cancel()
+
+	select {
+	case err := <-served:
+		if err == nil {
+			t.Error("expected shutdown timeout error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not honour the shutdown timeout")
+	}
+}
+
+func TestServe_ReturnsListenErrors(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	err = Serve(context.Background(), NewDeadline(time.Second), New(l.Addr().String(), http.NotFoundHandler(), testConfig()))
+	if err == nil {
+		t.Fatal("expected error when the port is already in use")
+	}
+}
+
+func TestDeadline_SharedByAllStages(t *testing.T) {
+	d := NewDeadline(time.Minute)
+	defer d.Cancel()
+
+	first, ok := d.Context().Deadline()
+	if !ok {
+		t.Fatal("expected the shutdown context to have a deadline")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	// as etapas seguintes não ganham um prazo novo
+	if second, _ := d.Context().Deadline(); !second.Equal(first) {
+		t.Errorf("expected every stage to share deadline %v, got %v", first, second)
+	}
+}
+
+func TestDeadline_SleepStopsAtDeadline(t *testing.T) {
+	d := NewDeadline(50 * time.Millisecond)
+	defer d.Cancel()
+
+	start := time.Now()
+	d.Sleep(time.Minute)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Sleep to stop at the deadline, took %v", elapsed)
+	}
+}
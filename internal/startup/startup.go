@@ -0,0 +1,76 @@
+// Package startup valida a configuração do servidor e interpreta as listas que
+// dependem de outros pacotes. O servidor e o "ratelimitctl validate" usam a
+// mesma função, então a CLI só aceita o que o servidor aceitaria.
+package startup
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/envoy"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+	"github.com/alexduzi/labratelimiter/internal/proxy"
+	"github.com/alexduzi/labratelimiter/internal/storage/backends"
+)
+
+// Settings são as listas da configuração já interpretadas
+type Settings struct {
+	Policies       []limiter.Policy
+	TrustedProxies middleware.TrustedProxies
+	// Upstreams fica vazio quando o servidor não é um proxy
+	Upstreams []proxy.Route
+}
+
+// Parse valida a configuração e interpreta POLICIES, TRUSTED_PROXIES,
+// UPSTREAM_URL/UPSTREAM_ROUTES, RLS_DESCRIPTORS e EXTAUTHZ_POLICIES, sem abrir
+// conexões. Os erros de todas as variáveis são retornados juntos.
+func Parse(cfg *config.Config) (*Settings, error) {
+	s := &Settings{}
+	errs := []error{cfg.Validate(), backends.Validate(cfg)}
+
+	policies, policiesErr := limiter.ParsePolicies(cfg.Policies)
+	if policiesErr != nil {
+		errs = append(errs, fmt.Errorf("POLICIES: %w", policiesErr))
+	}
+	s.Policies = policies
+
+	trusted, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %w", err))
+	}
+	s.TrustedProxies = trusted
+
+	upstreams, err := proxy.ParseRoutes(cfg.UpstreamURL, cfg.UpstreamRoutes)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	s.Upstreams = upstreams
+
+	// sem POLICIES válido toda política customizada pareceria desconhecida
+	if policiesErr == nil {
+		known := map[string]bool{limiter.KindIP: true, limiter.KindToken: true}
+		for _, p := range policies {
+			known[p.Name] = true
+		}
+
+		if cfg.ExtAuthzPort != "" {
+			for _, name := range cfg.ExtAuthzPolicies {
+				if !known[name] {
+					errs = append(errs, fmt.Errorf("EXTAUTHZ_POLICIES: unknown policy %q", name))
+				}
+			}
+		}
+		if cfg.RLSPort != "" {
+			if _, err := envoy.ParseDescriptorRules(cfg.RLSDescriptorRules, func(policy string) bool { return known[policy] }); err != nil {
+				errs = append(errs, fmt.Errorf("RLS_DESCRIPTORS: %w", err))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
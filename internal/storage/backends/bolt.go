@@ -0,0 +1,37 @@
+package backends
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func init() {
+	Register("bolt", Backend{
+		Validate: func(cfg *config.Config) error {
+			if cfg.BoltPath == "" {
+				return errors.New("BOLT_PATH must not be empty")
+			}
+			if info, err := os.Stat(filepath.Dir(cfg.BoltPath)); err != nil || !info.IsDir() {
+				return fmt.Errorf("BOLT_PATH directory %q does not exist", filepath.Dir(cfg.BoltPath))
+			}
+			if cfg.BoltCompactionInterval < 0 {
+				return errors.New("BOLT_COMPACTION_INTERVAL must not be negative")
+			}
+			if cfg.BoltBatchDelay < 0 {
+				return errors.New("BOLT_BATCH_DELAY must not be negative")
+			}
+			return nil
+		},
+		Open: func(cfg *config.Config) (storage.Storage, error) {
+			return storage.NewBoltStorage(cfg.BoltPath,
+				storage.WithBoltCompactionInterval(cfg.BoltCompactionInterval),
+				storage.WithBoltBatchDelay(cfg.BoltBatchDelay),
+			)
+		},
+	})
+}
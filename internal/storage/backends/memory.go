@@ -0,0 +1,35 @@
+package backends
+
+import (
+	"errors"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func init() {
+	Register("memory", Backend{
+		Validate: func(cfg *config.Config) error {
+			if cfg.MemoryMaxEntries < 0 {
+				return errors.New("MEMORY_MAX_ENTRIES must not be negative")
+			}
+			if cfg.MemoryCleanupInterval < 0 {
+				return errors.New("MEMORY_CLEANUP_INTERVAL must not be negative")
+			}
+			if cfg.MemoryShards < 1 {
+				return errors.New("MEMORY_SHARDS must be at least 1")
+			}
+			return nil
+		},
+		Open: func(cfg *config.Config) (storage.Storage, error) {
+			opts := []storage.MemoryOption{
+				storage.WithMemoryMaxEntries(cfg.MemoryMaxEntries),
+				storage.WithMemoryCleanupInterval(cfg.MemoryCleanupInterval),
+			}
+			if cfg.MemoryShards == 1 {
+				return storage.NewMemoryStorage(opts...), nil
+			}
+			return storage.NewShardedMemoryStorage(cfg.MemoryShards, opts...), nil
+		},
+	})
+}
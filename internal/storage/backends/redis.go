@@ -0,0 +1,27 @@
+package backends
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+func init() {
+	Register("redis", Backend{
+		Validate: func(cfg *config.Config) error {
+			if _, _, err := net.SplitHostPort(cfg.RedisAddr); err != nil {
+				return fmt.Errorf("REDIS_ADDR: %w", err)
+			}
+			if cfg.RedisDB < 0 {
+				return errors.New("REDIS_DB must not be negative")
+			}
+			return nil
+		},
+		Open: func(cfg *config.Config) (storage.Storage, error) {
+			return storage.NewRedisStorage(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+		},
+	})
+}
@@ -0,0 +1,72 @@
+// Package backends registra os storages que podem ser selecionados pela
+// configuração (STORAGE_BACKEND) e os abre a partir dela.
+package backends
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+// Backend descreve como validar a configuração e abrir um storage
+type Backend struct {
+	// Validate checa as opções específicas do backend sem abrir conexões
+	Validate func(cfg *config.Config) error
+	Open     func(cfg *config.Config) (storage.Storage, error)
+}
+
+var backends = map[string]Backend{}
+
+// Register disponibiliza um backend para seleção via STORAGE_BACKEND.
+// Deve ser chamado em init(); registrar o mesmo nome duas vezes causa panic.
+func Register(name string, backend Backend) {
+	if _, exists := backends[name]; exists {
+		panic(fmt.Sprintf("backends: backend %q already registered", name))
+	}
+	backends[name] = backend
+}
+
+// Names retorna os nomes dos backends registrados em ordem alfabética
+func Names() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checa se o backend configurado existe e se suas opções são válidas
+func Validate(cfg *config.Config) error {
+	backend, exists := backends[cfg.StorageBackend]
+	if !exists {
+		return fmt.Errorf("unknown storage backend %q (available: %s)", cfg.StorageBackend, strings.Join(Names(), ", "))
+	}
+
+	if backend.Validate == nil {
+		return nil
+	}
+
+	if err := backend.Validate(cfg); err != nil {
+		return fmt.Errorf("invalid %s storage config: %w", cfg.StorageBackend, err)
+	}
+
+	return nil
+}
+
+// New valida a configuração e abre o backend selecionado em cfg.StorageBackend
+func New(cfg *config.Config) (storage.Storage, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+
+	store, err := backends[cfg.StorageBackend].Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s storage: %w", cfg.StorageBackend, err)
+	}
+
+	return store, nil
+}
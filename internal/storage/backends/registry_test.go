@@ -0,0 +1,58 @@
+package backends
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+)
+
+func TestNew_OpensBackendsWithoutRedis(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+	}{
+		{name: "memory", cfg: config.Config{StorageBackend: "memory", MemoryShards: 1}},
+		{name: "sharded memory", cfg: config.Config{StorageBackend: "memory", MemoryShards: 4}},
+		{name: "bolt", cfg: config.Config{StorageBackend: "bolt", BoltPath: filepath.Join(t.TempDir(), "ratelimiter.db")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(&tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Errorf("unexpected error on close: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_RejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{name: "unknown backend", cfg: config.Config{StorageBackend: "etcd"}, wantErr: "unknown storage backend"},
+		{name: "redis without port", cfg: config.Config{StorageBackend: "redis", RedisAddr: "localhost"}, wantErr: "REDIS_ADDR"},
+		{name: "redis negative db", cfg: config.Config{StorageBackend: "redis", RedisAddr: "localhost:6379", RedisDB: -1}, wantErr: "REDIS_DB"},
+		{name: "memory without shards", cfg: config.Config{StorageBackend: "memory"}, wantErr: "MEMORY_SHARDS"},
+		{name: "bolt missing directory", cfg: config.Config{StorageBackend: "bolt", BoltPath: "/does/not/exist/ratelimiter.db"}, wantErr: "BOLT_PATH"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.cfg)
+			if err == nil {
+				t.Fatal("expected validation error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %q", tt.wantErr, err)
+			}
+		})
+	}
+}
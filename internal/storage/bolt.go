@@ -0,0 +1,448 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	bolt "go.etcd.io/bbolt"
+)
+
+const DefaultBoltCompactionInterval = time.Minute
+
+// DefaultBoltBatchDelay é quanto uma escrita espera por outras para dividir a
+// mesma transação (e o mesmo fsync)
+const DefaultBoltBatchDelay = time.Millisecond
+
+var boltBucket = []byte("ratelimiter")
+
+// boltRecord é gravado como 4 int64 big-endian: contador, início da janela,
+// fim da janela e fim do bloqueio (tempos em nanossegundos Unix, zero = ausente)
+type boltRecord struct {
+	counter      int64
+	windowStart  int64
+	windowEnd    int64
+	blockedUntil int64
+}
+
+const boltRecordSize = 32
+
+func (r boltRecord) encode() []byte {
+	buf := make([]byte, boltRecordSize)
+	binary.BigEndian.PutUint64(buf[0:], uint64(r.counter))
+	binary.BigEndian.PutUint64(buf[8:], uint64(r.windowStart))
+	binary.BigEndian.PutUint64(buf[16:], uint64(r.windowEnd))
+	binary.BigEndian.PutUint64(buf[24:], uint64(r.blockedUntil))
+	return buf
+}
+
+func decodeBoltRecord(buf []byte) (boltRecord, error) {
+	if len(buf) != boltRecordSize {
+		return boltRecord{}, fmt.Errorf("invalid record size %d", len(buf))
+	}
+
+	return boltRecord{
+		counter:      int64(binary.BigEndian.Uint64(buf[0:])),
+		windowStart:  int64(binary.BigEndian.Uint64(buf[8:])),
+		windowEnd:    int64(binary.BigEndian.Uint64(buf[16:])),
+		blockedUntil: int64(binary.BigEndian.Uint64(buf[24:])),
+	}, nil
+}
+
+func (r boltRecord) expired(now int64) bool {
+	return now >= r.windowEnd && now >= r.blockedUntil
+}
+
+// increment soma n ao contador, começando uma janela nova se a atual já terminou
+func (r *boltRecord) increment(n int64, window time.Duration, now int64) {
+	if now >= r.windowEnd {
+		r.counter = n
+		r.windowStart = now
+		r.windowEnd = now + int64(window)
+	} else {
+		r.counter += n
+	}
+}
+
+// state retorna o estado do registro, sem a janela e o bloqueio já expirados
+func (r boltRecord) state(now int64) State {
+	var state State
+	if now < r.windowEnd {
+		state.Count = r.counter
+		state.WindowStart = time.Unix(0, r.windowStart)
+		state.WindowEnd = time.Unix(0, r.windowEnd)
+	}
+	if now < r.blockedUntil {
+		state.BlockedUntil = time.Unix(0, r.blockedUntil)
+	}
+	return state
+}
+
+// BoltStorage persiste contadores e bloqueios em um arquivo bbolt local, para
+// deployments de um único nó sem Redis que não podem perder os bloqueios ao reiniciar
+type BoltStorage struct {
+	db    *bolt.DB
+	clock clock.Clock
+
+	compactionInterval time.Duration
+	batchDelay         time.Duration
+	stop               chan struct{}
+	done               chan struct{}
+	closeOnce          sync.Once
+}
+
+type BoltOption func(*BoltStorage)
+
+// WithBoltCompactionInterval define de quanto em quanto tempo os registros
+// expirados são removidos do arquivo. Zero desativa a compactação.
+func WithBoltCompactionInterval(d time.Duration) BoltOption {
+	return func(b *BoltStorage) {
+		b.compactionInterval = d
+	}
+}
+
+// WithBoltBatchDelay define quanto uma escrita espera por outras concorrentes
+// para gravá-las na mesma transação. Cada transação faz um fsync, então sem
+// lotes o throughput fica limitado à taxa de fsync do disco; zero desativa os
+// lotes.
+func WithBoltBatchDelay(d time.Duration) BoltOption {
+	return func(b *BoltStorage) {
+		b.batchDelay = d
+	}
+}
+
+// WithBoltClock substitui o relógio usado para janelas e bloqueios
+func WithBoltClock(c clock.Clock) BoltOption {
+	return func(b *BoltStorage) {
+		b.clock = c
+	}
+}
+
+func NewBoltStorage(path string, opts ...BoltOption) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bolt bucket: %w", err)
+	}
+
+	b := &BoltStorage{
+		db:                 db,
+		clock:              clock.Real(),
+		compactionInterval: DefaultBoltCompactionInterval,
+		batchDelay:         DefaultBoltBatchDelay,
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+	db.MaxBatchDelay = b.batchDelay
+
+	if b.compactionInterval > 0 {
+		go b.compactor()
+	} else {
+		close(b.done)
+	}
+
+	return b, nil
+}
+
+func (b *BoltStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return b.IncrementBy(ctx, key, 1, window)
+}
+
+func (b *BoltStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	var counter int64
+
+	err := b.update(key, func(r *boltRecord, now int64) {
+		r.increment(n, window, now)
+		counter = r.counter
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment: %w", err)
+	}
+
+	return counter, nil
+}
+
+func (b *BoltStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (State, bool, error) {
+	var (
+		state State
+		ok    bool
+	)
+
+	err := b.update(key, func(r *boltRecord, now int64) {
+		state, ok = r.state(now), false
+		if state.Blocked() || state.Count+n > limit {
+			return
+		}
+
+		r.increment(n, window, now)
+		state, ok = r.state(now), true
+	})
+	if err != nil {
+		return State{}, false, fmt.Errorf("failed to take: %w", err)
+	}
+
+	return state, ok, nil
+}
+
+func (b *BoltStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		value := bucket.Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+
+		r, err := decodeBoltRecord(value)
+		if err != nil {
+			return err
+		}
+
+		if b.clock.Now().UnixNano() >= r.windowEnd || r.windowStart != windowStart.UnixNano() {
+			return nil
+		}
+
+		r.counter -= min(n, r.counter)
+		return bucket.Put([]byte(key), r.encode())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refund: %w", err)
+	}
+
+	return nil
+}
+
+func (b *BoltStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	var blocked bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltBucket).Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+
+		r, err := decodeBoltRecord(value)
+		if err != nil {
+			return err
+		}
+
+		blocked = b.clock.Now().UnixNano() < r.blockedUntil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check if blocked: %w", err)
+	}
+
+	return blocked, nil
+}
+
+func (b *BoltStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	err := b.update(key, func(r *boltRecord, now int64) {
+		r.blockedUntil = now + int64(duration)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to block: %w", err)
+	}
+
+	return nil
+}
+
+func (b *BoltStorage) Unblock(ctx context.Context, key string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		value := bucket.Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+
+		r, err := decodeBoltRecord(value)
+		if err != nil {
+			return err
+		}
+
+		r.blockedUntil = 0
+		return bucket.Put([]byte(key), r.encode())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unblock: %w", err)
+	}
+
+	return nil
+}
+
+func (b *BoltStorage) GetState(ctx context.Context, key string) (State, error) {
+	var state State
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(boltBucket).Get([]byte(key))
+		if value == nil {
+			return nil
+		}
+
+		r, err := decodeBoltRecord(value)
+		if err != nil {
+			return err
+		}
+
+		state = r.state(b.clock.Now().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return State{}, fmt.Errorf("failed to get state: %w", err)
+	}
+
+	return state, nil
+}
+
+func (b *BoltStorage) ListBlocked(ctx context.Context, prefix string) ([]BlockedKey, error) {
+	var blocked []BlockedKey
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		now := b.clock.Now().UnixNano()
+		cursor := tx.Bucket(boltBucket).Cursor()
+
+		// as chaves do bbolt são ordenadas, então basta percorrer a partir do prefixo
+		for k, v := cursor.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = cursor.Next() {
+			r, err := decodeBoltRecord(v)
+			if err != nil {
+				return err
+			}
+			if now < r.blockedUntil {
+				blocked = append(blocked, BlockedKey{Key: string(k), BlockedUntil: time.Unix(0, r.blockedUntil)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocked keys: %w", err)
+	}
+
+	return blocked, nil
+}
+
+func (b *BoltStorage) Reset(ctx context.Context, key string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset: %w", err)
+	}
+
+	return nil
+}
+
+// Ping abre uma transação de leitura, que falha se o arquivo já foi fechado
+func (b *BoltStorage) Ping(ctx context.Context) error {
+	if err := b.db.View(func(tx *bolt.Tx) error { return nil }); err != nil {
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+
+	return nil
+}
+
+func (b *BoltStorage) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+
+	return b.db.Close()
+}
+
+// update aplica fn ao registro da chave. Com lotes, escritas concorrentes
+// dividem a transação; fn pode ser executada de novo se o lote falhar, então só
+// deve alterar r e variáveis sobrescritas a cada execução.
+func (b *BoltStorage) update(key string, fn func(r *boltRecord, now int64)) error {
+	write := b.db.Update
+	if b.batchDelay > 0 {
+		write = b.db.Batch
+	}
+
+	return write(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		var r boltRecord
+		if value := bucket.Get([]byte(key)); value != nil {
+			decoded, err := decodeBoltRecord(value)
+			if err != nil {
+				return err
+			}
+			r = decoded
+		}
+
+		fn(&r, b.clock.Now().UnixNano())
+
+		return bucket.Put([]byte(key), r.encode())
+	})
+}
+
+func (b *BoltStorage) compactor() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.compactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.deleteExpired()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *BoltStorage) deleteExpired() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		now := b.clock.Now().UnixNano()
+		bucket := tx.Bucket(boltBucket)
+
+		// as chaves são coletadas antes da remoção porque apagar durante a
+		// iteração com o cursor pode pular itens
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			r, err := decodeBoltRecord(v)
+			if err != nil || r.expired(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BoltStorage) size() int {
+	n := 0
+	b.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(boltBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoltStorage_BlockSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimiter.db")
+	ctx := context.Background()
+
+	store, err := NewBoltStorage(path, WithBoltCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("failed to open bolt storage: %v", err)
+	}
+
+	if err := store.Block(ctx, "ip:1.2.3.4", time.Hour); err != nil {
+		t.Fatalf("failed to block: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		store.Increment(ctx, "token:abc", time.Hour)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("failed to close bolt storage: %v", err)
+	}
+
+	store, err = NewBoltStorage(path, WithBoltCompactionInterval(0))
+	if err != nil {
+		t.Fatalf("failed to reopen bolt storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	blocked, err := store.IsBlocked(ctx, "ip:1.2.3.4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !blocked {
+		t.Error("expected block to survive a restart")
+	}
+
+	count, err := store.Increment(ctx, "token:abc", time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("expected counter to survive a restart with value 4, got %d", count)
+	}
+}
+
+func TestBoltStorage_CompactionRemovesExpiredRecords(t *testing.T) {
+	store, err := NewBoltStorage(filepath.Join(t.TempDir(), "ratelimiter.db"), WithBoltCompactionInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to open bolt storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	store.Increment(ctx, "ip:expired", 10*time.Millisecond)
+	store.Block(ctx, "ip:blocked", time.Hour)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.size() > 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if size := store.size(); size != 1 {
+		t.Fatalf("expected only the blocked record to remain, got %d records", size)
+	}
+}
+
+func benchmarkBoltIncrement(b *testing.B, delay time.Duration) {
+	store, err := NewBoltStorage(filepath.Join(b.TempDir(), "bench.db"), WithBoltCompactionInterval(0), WithBoltBatchDelay(delay))
+	if err != nil {
+		b.Fatalf("failed to open bolt storage: %v", err)
+	}
+
+	benchmarkIncrement(b, store)
+}
+
+// Cada transação faz um fsync; compare com e sem lotes em -cpu=1,16
+func BenchmarkBoltStorage_Increment(b *testing.B) {
+	benchmarkBoltIncrement(b, 0)
+}
+
+func BenchmarkBoltStorage_IncrementBatch(b *testing.B) {
+	benchmarkBoltIncrement(b, DefaultBoltBatchDelay)
+}
@@ -0,0 +1,31 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/storage/storagetest"
+)
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.NewMemoryStorage()
+	})
+}
+
+func TestShardedMemoryStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return storage.NewShardedMemoryStorage(storage.DefaultMemoryShards)
+	})
+}
+
+func TestBoltStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		store, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "ratelimiter.db"))
+		if err != nil {
+			t.Fatalf("failed to open bolt storage: %v", err)
+		}
+		return store
+	})
+}
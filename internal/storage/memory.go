@@ -1,100 +1,444 @@
 package storage
 
 import (
+	"container/heap"
+	"container/list"
 	"context"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+)
+
+const (
+	DefaultMemoryCleanupInterval = time.Minute
+	DefaultMemoryMaxEntries      = 100_000
 )
 
 type memoryEntry struct {
+	key          string
 	counter      int64
 	windowStart  time.Time
+	windowEnd    time.Time
 	blockedUntil *time.Time
+	// element é a posição em MemoryStorage.lru (sem bloqueio) e index a
+	// posição em MemoryStorage.blocked (com bloqueio, -1 fora dele)
+	element *list.Element
+	index   int
+}
+
+// expired indica se a janela e o bloqueio da entrada já terminaram
+func (e *memoryEntry) expired(now time.Time) bool {
+	if now.Before(e.windowEnd) {
+		return false
+	}
+
+	return e.blockedUntil == nil || !now.Before(*e.blockedUntil)
+}
+
+// state retorna o estado da entrada, sem a janela e o bloqueio já expirados
+func (e *memoryEntry) state(now time.Time) State {
+	var state State
+	if now.Before(e.windowEnd) {
+		state.Count = e.counter
+		state.WindowStart = e.windowStart
+		state.WindowEnd = e.windowEnd
+	}
+	if e.blockedUntil != nil && now.Before(*e.blockedUntil) {
+		state.BlockedUntil = *e.blockedUntil
+	}
+	return state
 }
 
 type MemoryStorage struct {
 	mu   sync.Mutex
 	data map[string]*memoryEntry
+	// lru mantém as chaves sem bloqueio da mais recente (frente) para a menos
+	// recente (fundo); as bloqueadas ficam em blocked, para que o descarte as evite
+	lru     *list.List
+	blocked blockHeap
+
+	opts memoryOptions
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type memoryOptions struct {
+	maxEntries      int
+	cleanupInterval time.Duration
+	clock           clock.Clock
 }
 
-func NewMemoryStorage() *MemoryStorage {
-	return &MemoryStorage{
+type MemoryOption func(*memoryOptions)
+
+func newMemoryOptions(opts []MemoryOption) memoryOptions {
+	o := memoryOptions{
+		maxEntries:      DefaultMemoryMaxEntries,
+		cleanupInterval: DefaultMemoryCleanupInterval,
+		clock:           clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithMemoryMaxEntries limita o número de chaves mantidas em memória. Quando o
+// limite é atingido a chave sem bloqueio usada há mais tempo é descartada; um
+// bloqueio ativo só é descartado se todas as chaves estiverem bloqueadas. Zero
+// desativa o limite.
+func WithMemoryMaxEntries(n int) MemoryOption {
+	return func(o *memoryOptions) {
+		o.maxEntries = n
+	}
+}
+
+// WithMemoryCleanupInterval define de quanto em quanto tempo as entradas
+// expiradas são removidas. Zero desativa a limpeza em background.
+func WithMemoryCleanupInterval(d time.Duration) MemoryOption {
+	return func(o *memoryOptions) {
+		o.cleanupInterval = d
+	}
+}
+
+// WithMemoryClock substitui o relógio usado para janelas e bloqueios
+func WithMemoryClock(c clock.Clock) MemoryOption {
+	return func(o *memoryOptions) {
+		o.clock = c
+	}
+}
+
+func NewMemoryStorage(opts ...MemoryOption) *MemoryStorage {
+	return newMemoryStorage(newMemoryOptions(opts))
+}
+
+func newMemoryStorage(opts memoryOptions) *MemoryStorage {
+	m := &MemoryStorage{
 		data: make(map[string]*memoryEntry),
+		lru:  list.New(),
+		opts: opts,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
 	}
+
+	if m.opts.cleanupInterval > 0 {
+		go m.janitor()
+	} else {
+		close(m.done)
+	}
+
+	return m
 }
 
 func (m *MemoryStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return m.IncrementBy(ctx, key, 1, window)
+}
+
+func (m *MemoryStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	now := time.Now()
+	return m.increment(key, n, window, m.opts.clock.Now()).counter, nil
+}
+
+func (m *MemoryStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.opts.clock.Now()
+
+	var state State
+	if entry, exists := m.data[key]; exists {
+		m.touch(entry)
+		state = entry.state(now)
+	}
+	if state.Blocked() || state.Count+n > limit {
+		return state, false, nil
+	}
+
+	return m.increment(key, n, window, now).state(now), true, nil
+}
 
+// increment soma n ao contador da chave, começando uma janela nova se a atual
+// já terminou. Deve ser chamado com o mutex travado.
+func (m *MemoryStorage) increment(key string, n int64, window time.Duration, now time.Time) *memoryEntry {
 	entry, exists := m.data[key]
 	if !exists {
-		m.data[key] = &memoryEntry{
-			counter:     1,
+		entry = &memoryEntry{
+			key:         key,
+			counter:     n,
 			windowStart: now,
+			windowEnd:   now.Add(window),
 		}
-		return 1, nil
+		m.insert(entry)
+		return entry
 	}
 
-	if now.Sub(entry.windowStart) >= window {
-		entry.counter = 1
+	m.touch(entry)
+
+	if !now.Before(entry.windowEnd) {
+		entry.counter = n
 		entry.windowStart = now
+		entry.windowEnd = now.Add(window)
 	} else {
-		entry.counter++
+		entry.counter += n
 	}
 
-	return entry.counter, nil
+	return entry
 }
 
-func (m *MemoryStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+func (m *MemoryStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	entry, exists := m.data[key]
-	if !exists {
-		return false, nil
+	if !exists || !m.opts.clock.Now().Before(entry.windowEnd) || !entry.windowStart.Equal(windowStart) {
+		return nil
 	}
 
-	if entry.blockedUntil == nil {
+	entry.counter -= min(n, entry.counter)
+	return nil
+}
+
+func (m *MemoryStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.data[key]
+	if !exists {
 		return false, nil
 	}
 
-	if time.Now().Before(*entry.blockedUntil) {
-		return true, nil
+	if entry.blockedUntil != nil && !m.opts.clock.Now().Before(*entry.blockedUntil) {
+		entry.blockedUntil = nil
 	}
+	m.touch(entry)
 
-	entry.blockedUntil = nil
-	return false, nil
+	return entry.blockedUntil != nil, nil
 }
 
 func (m *MemoryStorage) Block(ctx context.Context, key string, duration time.Duration) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	blockedUntil := time.Now().Add(duration)
+	blockedUntil := m.opts.clock.Now().Add(duration)
 
 	entry, exists := m.data[key]
 	if !exists {
-		m.data[key] = &memoryEntry{
+		m.insert(&memoryEntry{
+			key:          key,
 			blockedUntil: &blockedUntil,
-		}
+		})
 		return nil
 	}
 
 	entry.blockedUntil = &blockedUntil
+	if entry.index >= 0 {
+		heap.Fix(&m.blocked, entry.index)
+	}
+	m.touch(entry)
 	return nil
 }
 
+func (m *MemoryStorage) Unblock(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, exists := m.data[key]; exists {
+		entry.blockedUntil = nil
+		m.touch(entry)
+	}
+	return nil
+}
+
+func (m *MemoryStorage) GetState(ctx context.Context, key string) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.data[key]
+	if !exists {
+		return State{}, nil
+	}
+
+	return entry.state(m.opts.clock.Now()), nil
+}
+
+func (m *MemoryStorage) ListBlocked(ctx context.Context, prefix string) ([]BlockedKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.opts.clock.Now()
+
+	var blocked []BlockedKey
+	for key, entry := range m.data {
+		if entry.blockedUntil == nil || !now.Before(*entry.blockedUntil) || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		blocked = append(blocked, BlockedKey{Key: key, BlockedUntil: *entry.blockedUntil})
+	}
+
+	return blocked, nil
+}
+
 func (m *MemoryStorage) Reset(ctx context.Context, key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	delete(m.data, key)
+	if entry, exists := m.data[key]; exists {
+		m.remove(entry)
+	}
 	return nil
 }
 
+// Ping só falha depois de Close, já que não há conexão a verificar
+func (m *MemoryStorage) Ping(ctx context.Context) error {
+	select {
+	case <-m.stop:
+		return ErrClosed
+	default:
+		return nil
+	}
+}
+
+// Close interrompe a limpeza em background e aguarda a goroutine terminar
 func (m *MemoryStorage) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	<-m.done
 	return nil
 }
+
+// insert adiciona uma nova entrada, descartando outras se o limite for excedido.
+// Deve ser chamado com o mutex travado.
+func (m *MemoryStorage) insert(entry *memoryEntry) {
+	entry.index = -1
+	m.data[entry.key] = entry
+	m.touch(entry)
+
+	for m.opts.maxEntries > 0 && len(m.data) > m.opts.maxEntries {
+		m.evict(m.opts.clock.Now())
+	}
+}
+
+// touch coloca a entrada em lru ou blocked conforme o bloqueio, marcando-a como
+// a mais recente em lru. Deve ser chamado com o mutex travado.
+func (m *MemoryStorage) touch(entry *memoryEntry) {
+	if entry.blockedUntil != nil {
+		if entry.element != nil {
+			m.lru.Remove(entry.element)
+			entry.element = nil
+		}
+		if entry.index < 0 {
+			heap.Push(&m.blocked, entry)
+		}
+		return
+	}
+
+	if entry.index >= 0 {
+		heap.Remove(&m.blocked, entry.index)
+	}
+	if entry.element == nil {
+		entry.element = m.lru.PushFront(entry)
+	} else {
+		m.lru.MoveToFront(entry.element)
+	}
+}
+
+// evict descarta uma entrada: um bloqueio que já expirou, senão a entrada sem
+// bloqueio usada há mais tempo e, só quando todas estão bloqueadas, o bloqueio
+// que termina primeiro. Deve ser chamado com o mutex travado.
+func (m *MemoryStorage) evict(now time.Time) {
+	if len(m.blocked) > 0 && !now.Before(*m.blocked[0].blockedUntil) {
+		m.remove(m.blocked[0])
+		return
+	}
+
+	if oldest := m.lru.Back(); oldest != nil {
+		m.remove(oldest.Value.(*memoryEntry))
+		return
+	}
+
+	if len(m.blocked) > 0 {
+		m.remove(m.blocked[0])
+	}
+}
+
+// remove deve ser chamado com o mutex travado
+func (m *MemoryStorage) remove(entry *memoryEntry) {
+	if entry.element != nil {
+		m.lru.Remove(entry.element)
+	}
+	if entry.index >= 0 {
+		heap.Remove(&m.blocked, entry.index)
+	}
+	delete(m.data, entry.key)
+}
+
+// blockHeap ordena as entradas bloqueadas pelo fim do bloqueio
+type blockHeap []*memoryEntry
+
+func (h blockHeap) Len() int { return len(h) }
+
+func (h blockHeap) Less(i, j int) bool { return h[i].blockedUntil.Before(*h[j].blockedUntil) }
+
+func (h blockHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *blockHeap) Push(x any) {
+	entry := x.(*memoryEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *blockHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*h = old[:len(old)-1]
+	return entry
+}
+
+func (m *MemoryStorage) janitor() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.opts.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.deleteExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *MemoryStorage) deleteExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.opts.clock.Now()
+	for _, entry := range m.data {
+		if entry.expired(now) {
+			m.remove(entry)
+		}
+	}
+}
+
+func (m *MemoryStorage) size() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.data)
+}
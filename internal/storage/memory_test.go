@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+)
+
+func randomIP() string {
+	return fmt.Sprintf("%d.%d.%d.%d", rand.IntN(256), rand.IntN(256), rand.IntN(256), rand.IntN(256))
+}
+
+func TestMemoryStorage_StaysBoundedUnderRandomIPFlood(t *testing.T) {
+	const maxEntries = 1000
+
+	store := NewMemoryStorage(WithMemoryMaxEntries(maxEntries), WithMemoryCleanupInterval(0))
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	for i := 0; i < 50*maxEntries; i++ {
+		key := "ip:" + randomIP()
+		if _, err := store.Increment(ctx, key, time.Minute); err != nil {
+			t.Fatalf("increment %d: unexpected error: %v", i, err)
+		}
+		if i%7 == 0 {
+			if err := store.Block(ctx, key, time.Minute); err != nil {
+				t.Fatalf("block %d: unexpected error: %v", i, err)
+			}
+		}
+
+		if size := store.size(); size > maxEntries {
+			t.Fatalf("after %d keys: expected at most %d entries, got %d", i+1, maxEntries, size)
+		}
+	}
+
+	if tracked := store.lru.Len() + store.blocked.Len(); tracked != store.size() {
+		t.Errorf("lru and blocked track %d entries but map has %d", tracked, store.size())
+	}
+}
+
+func TestMemoryStorage_EvictionKeepsActiveBlocks(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1_700_000_000, 0))
+	store := NewMemoryStorage(WithMemoryMaxEntries(3), WithMemoryCleanupInterval(0), WithMemoryClock(clk))
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	// "blocked" é a chave usada há mais tempo, mas o bloqueio ativo a protege
+	store.Block(ctx, "blocked", time.Hour)
+	store.Block(ctx, "expired", time.Second)
+	store.Increment(ctx, "a", time.Minute)
+	clk.Advance(2 * time.Second)
+
+	// o bloqueio expirado sai primeiro, depois a chave sem bloqueio
+	store.Increment(ctx, "b", time.Minute)
+	if _, exists := store.data["expired"]; exists {
+		t.Error("expected the expired block to be evicted first")
+	}
+	store.Increment(ctx, "c", time.Minute)
+	if _, exists := store.data["a"]; exists {
+		t.Error("expected the least recently used unblocked key to be evicted")
+	}
+	if blocked, _ := store.IsBlocked(ctx, "blocked"); !blocked {
+		t.Fatal("expected the active block to survive eviction")
+	}
+
+	// só com todas as chaves bloqueadas um bloqueio ativo é descartado, o que
+	// termina primeiro
+	store.Block(ctx, "b", time.Hour)
+	store.Block(ctx, "c", time.Hour)
+	store.Block(ctx, "d", time.Hour)
+	if size := store.size(); size != 3 {
+		t.Errorf("expected 3 entries, got %d", size)
+	}
+	if _, exists := store.data["blocked"]; exists {
+		t.Error("expected the block ending first to be evicted when every key is blocked")
+	}
+}
+
+func TestMemoryStorage_EvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryStorage(WithMemoryMaxEntries(2), WithMemoryCleanupInterval(0))
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	store.Increment(ctx, "a", time.Minute)
+	store.Increment(ctx, "b", time.Minute)
+	// "a" passa a ser a mais recente, então "b" deve ser descartada
+	store.Increment(ctx, "a", time.Minute)
+	store.Increment(ctx, "c", time.Minute)
+
+	if _, exists := store.data["b"]; exists {
+		t.Error("expected least recently used key \"b\" to be evicted")
+	}
+
+	count, _ := store.Increment(ctx, "a", time.Minute)
+	if count != 3 {
+		t.Errorf("expected key \"a\" to keep its counter, got %d", count)
+	}
+}
+
+func TestMemoryStorage_JanitorRemovesExpiredEntries(t *testing.T) {
+	store := NewMemoryStorage(WithMemoryCleanupInterval(10 * time.Millisecond))
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		store.Increment(ctx, fmt.Sprintf("ip:%d", i), 20*time.Millisecond)
+	}
+	store.Block(ctx, "ip:blocked", time.Hour)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for store.size() > 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if size := store.size(); size != 1 {
+		t.Fatalf("expected only the blocked entry to remain, got %d entries", size)
+	}
+
+	blocked, _ := store.IsBlocked(ctx, "ip:blocked")
+	if !blocked {
+		t.Error("expected entry still inside its block duration to survive cleanup")
+	}
+}
+
+func TestMemoryStorage_CloseStopsJanitor(t *testing.T) {
+	store := NewMemoryStorage(WithMemoryCleanupInterval(time.Millisecond))
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("unexpected error on close: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("unexpected error on second close: %v", err)
+	}
+
+	select {
+	case <-store.done:
+	default:
+		t.Fatal("expected janitor goroutine to be stopped after Close")
+	}
+
+	if err := store.Ping(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed from ping after close, got %v", err)
+	}
+}
@@ -2,7 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -29,24 +32,119 @@ func NewRedisStorage(addr, password string, db int) (*RedisStorage, error) {
 	return &RedisStorage{client: client}, nil
 }
 
+// incrementScript guarda o contador e os limites da janela (em ms Unix) em um
+// hash e só define o TTL no primeiro incremento, mantendo a janela fixa como nos
+// demais backends (renovar o TTL a cada incremento tornaria a janela deslizante).
+// A janela nova é detectada pela ausência de "start", já que um Refund pode
+// levar o contador de volta a zero dentro da mesma janela.
+// ARGV: janela em ms, agora em ms, incremento.
+var incrementScript = redis.NewScript(`
+local n = tonumber(ARGV[3])
+local count = redis.call("HINCRBY", KEYS[1], "count", n)
+if redis.call("HEXISTS", KEYS[1], "start") == 0 then
+	local now = tonumber(ARGV[2])
+	local window = tonumber(ARGV[1])
+	redis.call("HSET", KEYS[1], "start", now, "end", now + window)
+	redis.call("PEXPIRE", KEYS[1], window)
+end
+return count
+`)
+
+// refundScript só subtrai da janela que começou em ARGV[1] (ms Unix), sem
+// deixar o contador negativo; a chave expirada ou de outra janela fica como está.
+// ARGV: início da janela em ms, valor a devolver.
+var refundScript = redis.NewScript(`
+local start = redis.call("HGET", KEYS[1], "start")
+if not start or tonumber(start) ~= tonumber(ARGV[1]) then
+	return 0
+end
+local count = tonumber(redis.call("HGET", KEYS[1], "count"))
+local n = math.min(tonumber(ARGV[2]), count)
+redis.call("HINCRBY", KEYS[1], "count", -n)
+return n
+`)
+
+// takeScript é o incrementScript condicional: só soma se a chave não estiver
+// bloqueada (KEYS[2]) e o total não passar do limite, e retorna ok, contador,
+// início e fim da janela e fim do bloqueio, lidos no mesmo script.
+// ARGV: janela em ms, agora em ms, incremento, limite.
+var takeScript = redis.NewScript(`
+local window = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local blocked = tonumber(redis.call("GET", KEYS[2]) or "0")
+local state = redis.call("HMGET", KEYS[1], "count", "start", "end")
+local count, start, stop = 0, 0, 0
+if state[2] then
+	count, start, stop = tonumber(state[1]), tonumber(state[2]), tonumber(state[3])
+end
+if blocked > 0 or count + n > tonumber(ARGV[4]) then
+	return {0, count, start, stop, blocked}
+end
+if start == 0 then
+	start, stop = now, now + window
+	redis.call("HSET", KEYS[1], "count", n, "start", start, "end", stop)
+	redis.call("PEXPIRE", KEYS[1], window)
+	return {1, n, start, stop, 0}
+end
+return {1, redis.call("HINCRBY", KEYS[1], "count", n), start, stop, 0}
+`)
+
+const blockedSuffix = ":blocked"
+
+func blockedKey(key string) string {
+	return key + blockedSuffix
+}
+
 func (r *RedisStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
-	pipe := r.client.Pipeline()
+	return r.IncrementBy(ctx, key, 1, window)
+}
 
-	incr := pipe.Incr(ctx, key)
-	pipe.Expire(ctx, key, window)
+func (r *RedisStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	now := time.Now().UnixMilli()
 
-	_, err := pipe.Exec(ctx)
+	count, err := incrementScript.Run(ctx, r.client, []string{key}, window.Milliseconds(), now, n).Int64()
 	if err != nil {
 		return 0, fmt.Errorf("failed to increment: %w", err)
 	}
 
-	return incr.Val(), nil
+	return count, nil
 }
 
-func (r *RedisStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
-	blockedKey := key + ":blocked"
+func (r *RedisStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (State, bool, error) {
+	now := time.Now().UnixMilli()
+
+	values, err := takeScript.Run(ctx, r.client, []string{key, blockedKey(key)}, window.Milliseconds(), now, n, limit).Int64Slice()
+	if err != nil {
+		return State{}, false, fmt.Errorf("failed to take: %w", err)
+	}
+	if len(values) != 5 {
+		return State{}, false, fmt.Errorf("failed to take: unexpected reply %v", values)
+	}
+
+	var state State
+	if values[2] > 0 {
+		state.Count = values[1]
+		state.WindowStart = time.UnixMilli(values[2])
+		state.WindowEnd = time.UnixMilli(values[3])
+	}
+	if values[4] > 0 {
+		state.BlockedUntil = time.UnixMilli(values[4])
+	}
+
+	return state, values[0] == 1, nil
+}
+
+func (r *RedisStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
+	if err := refundScript.Run(ctx, r.client, []string{key}, windowStart.UnixMilli(), n).Err(); err != nil {
+		return fmt.Errorf("failed to refund: %w", err)
+	}
 
-	exists, err := r.client.Exists(ctx, blockedKey).Result()
+	return nil
+}
+
+func (r *RedisStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	exists, err := r.client.Exists(ctx, blockedKey(key)).Result()
 	if err != nil {
 		return false, fmt.Errorf("failed to check if blocked: %w", err)
 	}
@@ -55,9 +153,7 @@ func (r *RedisStorage) IsBlocked(ctx context.Context, key string) (bool, error)
 }
 
 func (r *RedisStorage) Block(ctx context.Context, key string, duration time.Duration) error {
-	blockedKey := key + ":blocked"
-
-	err := r.client.Set(ctx, blockedKey, time.Now().Add(duration).Unix(), duration).Err()
+	err := r.client.Set(ctx, blockedKey(key), time.Now().Add(duration).UnixMilli(), duration).Err()
 	if err != nil {
 		return fmt.Errorf("failed to block: %w", err)
 	}
@@ -65,8 +161,81 @@ func (r *RedisStorage) Block(ctx context.Context, key string, duration time.Dura
 	return nil
 }
 
+func (r *RedisStorage) Unblock(ctx context.Context, key string) error {
+	err := r.client.Del(ctx, blockedKey(key)).Err()
+	if err != nil {
+		return fmt.Errorf("failed to unblock: %w", err)
+	}
+
+	return nil
+}
+
+// ListBlocked usa SCAN para não travar o Redis com KEYS em databases grandes
+func (r *RedisStorage) ListBlocked(ctx context.Context, prefix string) ([]BlockedKey, error) {
+	var blocked []BlockedKey
+
+	iter := r.client.Scan(ctx, 0, escapeGlob(prefix)+"*"+blockedSuffix, 500).Iterator()
+
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		values, err := r.client.MGet(ctx, batch...).Result()
+		if err != nil {
+			return err
+		}
+
+		for i, value := range values {
+			// a chave pode ter expirado entre o SCAN e o MGET
+			until := parseRedisInt(value)
+			if until == 0 {
+				continue
+			}
+			blocked = append(blocked, BlockedKey{
+				Key:          strings.TrimSuffix(batch[i], blockedSuffix),
+				BlockedUntil: time.UnixMilli(until),
+			})
+		}
+
+		batch = batch[:0]
+		return nil
+	}
+
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("failed to list blocked keys: %w", err)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list blocked keys: %w", err)
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("failed to list blocked keys: %w", err)
+	}
+
+	return blocked, nil
+}
+
+// escapeGlob escapa os caracteres especiais do MATCH do SCAN
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
 func (r *RedisStorage) Reset(ctx context.Context, key string) error {
-	err := r.client.Del(ctx, key).Err()
+	err := r.client.Del(ctx, key, blockedKey(key)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to reset: %w", err)
 	}
@@ -74,6 +243,50 @@ func (r *RedisStorage) Reset(ctx context.Context, key string) error {
 	return nil
 }
 
+func (r *RedisStorage) GetState(ctx context.Context, key string) (State, error) {
+	pipe := r.client.Pipeline()
+
+	window := pipe.HMGet(ctx, key, "count", "start", "end")
+	blocked := pipe.Get(ctx, blockedKey(key))
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return State{}, fmt.Errorf("failed to get state: %w", err)
+	}
+
+	var state State
+
+	values := window.Val()
+	if len(values) == 3 && values[0] != nil {
+		state.Count = parseRedisInt(values[0])
+		state.WindowStart = time.UnixMilli(parseRedisInt(values[1]))
+		state.WindowEnd = time.UnixMilli(parseRedisInt(values[2]))
+	}
+
+	if until, err := blocked.Int64(); err == nil {
+		state.BlockedUntil = time.UnixMilli(until)
+	}
+
+	return state, nil
+}
+
+func parseRedisInt(value interface{}) int64 {
+	s, ok := value.(string)
+	if !ok {
+		return 0
+	}
+
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+func (r *RedisStorage) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+
+	return nil
+}
+
 func (r *RedisStorage) Close() error {
 	return r.client.Close()
 }
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+const DefaultMemoryShards = 32
+
+// ShardedMemoryStorage distribui as chaves entre vários MemoryStorage, cada um
+// com seu próprio mutex, para que requisições em cores diferentes não disputem
+// o mesmo lock. O limite de entradas é dividido igualmente entre os shards.
+type ShardedMemoryStorage struct {
+	seed   maphash.Seed
+	shards []*MemoryStorage
+
+	cleanupInterval time.Duration
+	stop            chan struct{}
+	done            chan struct{}
+	closeOnce       sync.Once
+}
+
+func NewShardedMemoryStorage(shards int, opts ...MemoryOption) *ShardedMemoryStorage {
+	if shards <= 0 {
+		shards = DefaultMemoryShards
+	}
+
+	o := newMemoryOptions(opts)
+
+	// cada shard guarda ao menos uma chave; com menos chaves que shards, menos
+	// shards mantêm o total dentro de maxEntries
+	if o.maxEntries > 0 && o.maxEntries < shards {
+		shards = o.maxEntries
+	}
+
+	// a limpeza é feita por uma única goroutine para todos os shards
+	shardOpts := o
+	shardOpts.cleanupInterval = 0
+	if o.maxEntries > 0 {
+		shardOpts.maxEntries = o.maxEntries / shards
+	}
+
+	s := &ShardedMemoryStorage{
+		seed:            maphash.MakeSeed(),
+		shards:          make([]*MemoryStorage, shards),
+		cleanupInterval: o.cleanupInterval,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+
+	for i := range s.shards {
+		s.shards[i] = newMemoryStorage(shardOpts)
+	}
+
+	if s.cleanupInterval > 0 {
+		go s.janitor()
+	} else {
+		close(s.done)
+	}
+
+	return s
+}
+
+func (s *ShardedMemoryStorage) shard(key string) *MemoryStorage {
+	return s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+}
+
+func (s *ShardedMemoryStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return s.shard(key).Increment(ctx, key, window)
+}
+
+func (s *ShardedMemoryStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	return s.shard(key).IncrementBy(ctx, key, n, window)
+}
+
+func (s *ShardedMemoryStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (State, bool, error) {
+	return s.shard(key).Take(ctx, key, n, limit, window)
+}
+
+func (s *ShardedMemoryStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
+	return s.shard(key).Refund(ctx, key, n, windowStart)
+}
+
+func (s *ShardedMemoryStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return s.shard(key).IsBlocked(ctx, key)
+}
+
+func (s *ShardedMemoryStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	return s.shard(key).Block(ctx, key, duration)
+}
+
+func (s *ShardedMemoryStorage) Unblock(ctx context.Context, key string) error {
+	return s.shard(key).Unblock(ctx, key)
+}
+
+func (s *ShardedMemoryStorage) GetState(ctx context.Context, key string) (State, error) {
+	return s.shard(key).GetState(ctx, key)
+}
+
+func (s *ShardedMemoryStorage) ListBlocked(ctx context.Context, prefix string) ([]BlockedKey, error) {
+	var blocked []BlockedKey
+	for _, shard := range s.shards {
+		keys, err := shard.ListBlocked(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, keys...)
+	}
+	return blocked, nil
+}
+
+func (s *ShardedMemoryStorage) Reset(ctx context.Context, key string) error {
+	return s.shard(key).Reset(ctx, key)
+}
+
+func (s *ShardedMemoryStorage) Ping(ctx context.Context) error {
+	select {
+	case <-s.stop:
+		return ErrClosed
+	default:
+		return nil
+	}
+}
+
+func (s *ShardedMemoryStorage) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+
+	for _, shard := range s.shards {
+		shard.Close()
+	}
+	return nil
+}
+
+func (s *ShardedMemoryStorage) janitor() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, shard := range s.shards {
+				shard.deleteExpired()
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *ShardedMemoryStorage) size() int {
+	total := 0
+	for _, shard := range s.shards {
+		total += shard.size()
+	}
+	return total
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedMemoryStorage_CountsAreExactUnderConcurrency(t *testing.T) {
+	store := NewShardedMemoryStorage(8, WithMemoryCleanupInterval(0))
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	const workers, perWorker = 16, 500
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := store.Increment(ctx, "token:shared", time.Minute); err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	count, _ := store.Increment(ctx, "token:shared", time.Minute)
+	if want := int64(workers*perWorker + 1); count != want {
+		t.Errorf("expected count %d, got %d", want, count)
+	}
+}
+
+func TestShardedMemoryStorage_StaysBounded(t *testing.T) {
+	tests := []struct {
+		maxEntries int
+		shards     int
+	}{
+		{1024, 16},
+		// menos chaves que shards
+		{5, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.maxEntries, tt.shards), func(t *testing.T) {
+			store := NewShardedMemoryStorage(tt.shards, WithMemoryMaxEntries(tt.maxEntries), WithMemoryCleanupInterval(0))
+			t.Cleanup(func() { store.Close() })
+
+			ctx := context.Background()
+
+			for i := 0; i < 20*tt.maxEntries; i++ {
+				store.Increment(ctx, "ip:"+randomIP(), time.Minute)
+			}
+
+			if size := store.size(); size > tt.maxEntries {
+				t.Errorf("expected at most %d entries, got %d", tt.maxEntries, size)
+			}
+		})
+	}
+}
+
+// Compare com: go test -run '^$' -bench . -cpu=1,4,16 ./internal/storage
+func benchmarkIncrement(b *testing.B, store Storage) {
+	b.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("ip:10.0.%d.%d", i/256, i%256)
+	}
+
+	var worker atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(worker.Add(1)) * 7919
+		for pb.Next() {
+			if _, err := store.Increment(ctx, keys[i%len(keys)], time.Second); err != nil {
+				// b.Fatal não pode ser chamado fora da goroutine do benchmark
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMemoryStorage_Increment(b *testing.B) {
+	benchmarkIncrement(b, NewMemoryStorage(WithMemoryCleanupInterval(0)))
+}
+
+func BenchmarkShardedMemoryStorage_Increment(b *testing.B) {
+	benchmarkIncrement(b, NewShardedMemoryStorage(DefaultMemoryShards, WithMemoryCleanupInterval(0)))
+}
@@ -1,14 +1,16 @@
 package storage
 
 import (
-	"context"
-	"time"
+	ratelimitstorage "github.com/alexduzi/labratelimiter/pkg/ratelimit/storage"
 )
 
-type Storage interface {
-	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
-	IsBlocked(ctx context.Context, key string) (bool, error)
-	Block(ctx context.Context, key string, duration time.Duration) error
-	Reset(ctx context.Context, key string) error
-	Close() error
-}
+// O contrato fica em pkg/ratelimit/storage para que storages externos possam
+// implementá-lo; os backends deste pacote usam os mesmos tipos
+type (
+	Storage    = ratelimitstorage.Storage
+	State      = ratelimitstorage.State
+	BlockedKey = ratelimitstorage.BlockedKey
+)
+
+// ErrClosed é retornado por Ping depois que o storage foi fechado
+var ErrClosed = ratelimitstorage.ErrClosed
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentStorage cria um span filho para cada operação do storage. backend
+// é registrado como atributo (ex.: "redis", "memory").
+func InstrumentStorage(s storage.Storage, backend string) storage.Storage {
+	return &tracedStorage{
+		next:    s,
+		tracer:  otel.Tracer(limiter.TracerName),
+		backend: attribute.String("ratelimiter.storage.backend", backend),
+	}
+}
+
+type tracedStorage struct {
+	next    storage.Storage
+	tracer  trace.Tracer
+	backend attribute.KeyValue
+}
+
+func (s *tracedStorage) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "ratelimiter.storage."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(s.backend),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	ctx, span := s.start(ctx, "increment")
+	count, err := s.next.Increment(ctx, key, window)
+	span.SetAttributes(attribute.Int64("ratelimiter.count", count))
+	end(span, err)
+	return count, err
+}
+
+func (s *tracedStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	ctx, span := s.start(ctx, "increment")
+	count, err := s.next.IncrementBy(ctx, key, n, window)
+	span.SetAttributes(attribute.Int64("ratelimiter.cost", n), attribute.Int64("ratelimiter.count", count))
+	end(span, err)
+	return count, err
+}
+
+func (s *tracedStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (storage.State, bool, error) {
+	ctx, span := s.start(ctx, "take")
+	state, ok, err := s.next.Take(ctx, key, n, limit, window)
+	span.SetAttributes(attribute.Int64("ratelimiter.cost", n), attribute.Int64("ratelimiter.count", state.Count), attribute.Bool("ratelimiter.taken", ok))
+	end(span, err)
+	return state, ok, err
+}
+
+func (s *tracedStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
+	ctx, span := s.start(ctx, "refund")
+	err := s.next.Refund(ctx, key, n, windowStart)
+	span.SetAttributes(attribute.Int64("ratelimiter.cost", n))
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	ctx, span := s.start(ctx, "is_blocked")
+	blocked, err := s.next.IsBlocked(ctx, key)
+	span.SetAttributes(attribute.Bool("ratelimiter.blocked", blocked))
+	end(span, err)
+	return blocked, err
+}
+
+func (s *tracedStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	ctx, span := s.start(ctx, "block")
+	err := s.next.Block(ctx, key, duration)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Unblock(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "unblock")
+	err := s.next.Unblock(ctx, key)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Reset(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "reset")
+	err := s.next.Reset(ctx, key)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) GetState(ctx context.Context, key string) (storage.State, error) {
+	ctx, span := s.start(ctx, "get_state")
+	state, err := s.next.GetState(ctx, key)
+	end(span, err)
+	return state, err
+}
+
+func (s *tracedStorage) ListBlocked(ctx context.Context, prefix string) ([]storage.BlockedKey, error) {
+	ctx, span := s.start(ctx, "list_blocked")
+	keys, err := s.next.ListBlocked(ctx, prefix)
+	span.SetAttributes(attribute.Int("ratelimiter.blocked_keys", len(keys)))
+	end(span, err)
+	return keys, err
+}
+
+func (s *tracedStorage) Ping(ctx context.Context) error {
+	ctx, span := s.start(ctx, "ping")
+	err := s.next.Ping(ctx)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Close() error {
+	return s.next.Close()
+}
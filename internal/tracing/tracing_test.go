@@ -0,0 +1,145 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/config"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"github.com/alexduzi/labratelimiter/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	if _, err := tracing.Setup(context.Background(), &config.Config{}); err != nil {
+		t.Fatalf("failed to setup propagation: %v", err)
+	}
+
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracing_DecisionSpanWithStorageChildren(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	cfg := &config.Config{IpLimitRps: 3, IpBlockDuration: time.Minute, TokenLimitRps: 4, TokenBlockDuration: time.Minute}
+
+	store := storage.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	rl := limiterconfig.New(tracing.InstrumentStorage(store, "memory"), cfg)
+	handler := tracing.Middleware(middleware.RateLimiter(rl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+
+	var decision sdktrace.ReadOnlySpan
+	children := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		if span.Name() == "ratelimiter.decision" {
+			decision = span
+		} else {
+			children[span.Name()] = span
+		}
+	}
+
+	if decision == nil {
+		t.Fatalf("expected a decision span, got %d spans", len(spans))
+	}
+	if got := decision.SpanContext().TraceID().String(); got != incomingTraceID {
+		t.Errorf("expected decision span to continue incoming trace %s, got %s", incomingTraceID, got)
+	}
+
+	wantAttrs := map[string]string{
+		"ratelimiter.type":    limiter.KindIP,
+		"ratelimiter.policy":  limiter.KindIP,
+		"ratelimiter.outcome": string(limiter.OutcomeAllowed),
+	}
+	for key, want := range wantAttrs {
+		if got, ok := attributeValue(decision, key); !ok || got.AsString() != want {
+			t.Errorf("attribute %s: expected %q, got %q", key, want, got.AsString())
+		}
+	}
+	if got, _ := attributeValue(decision, "ratelimiter.remaining"); got.AsInt64() != 2 {
+		t.Errorf("expected 2 remaining, got %d", got.AsInt64())
+	}
+
+	for _, name := range []string{"ratelimiter.storage.is_blocked", "ratelimiter.storage.increment"} {
+		child, ok := children[name]
+		if !ok {
+			t.Errorf("expected child span %s", name)
+			continue
+		}
+		if child.Parent().SpanID() != decision.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of the decision span", name)
+		}
+	}
+}
+
+func TestSetup_ExportsToOTLPEndpoint(t *testing.T) {
+	var received atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/traces" {
+			received.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(collector.Close)
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := tracing.Setup(context.Background(), &config.Config{
+		TracingEndpoint:    collector.URL + "/v1/traces",
+		TracingServiceName: "ratelimiter-test",
+		TracingSampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to setup tracing: %v", err)
+	}
+
+	_, span := otel.Tracer(limiter.TracerName).Start(context.Background(), "test")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown tracing: %v", err)
+	}
+
+	if received.Load() == 0 {
+		t.Error("expected spans to be exported to the collector on shutdown")
+	}
+}
@@ -0,0 +1,38 @@
+// Package chilimit adapta o rate limiter ao chi. As políticas por rota usam o
+// template completo do chi, ex.: "/users/{id}" ou "/api/v1/orders/*".
+package chilimit
+
+import (
+	"cmp"
+	"net/http"
+	"strings"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/go-chi/chi/v5"
+)
+
+// New cria o middleware do chi; funciona em r.Use (no router raiz, em
+// sub-routers e grupos) e em r.With
+func New(l *ratelimit.Limiter, opts ...ratelimit.RouteOption) func(http.Handler) http.Handler {
+	return ratelimit.NewRouteLimiter(l, opts...).Middleware(RoutePattern)
+}
+
+// RoutePattern retorna o template da rota da requisição. Em r.Use o chi ainda
+// não resolveu a rota, então ela é procurada a partir do router raiz, que é o
+// mesmo para os sub-routers.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	// em r.With a rota já foi resolvida
+	if n := len(rctx.RoutePatterns); n > 0 && !strings.HasSuffix(rctx.RoutePatterns[n-1], "/*") {
+		return rctx.RoutePattern()
+	}
+	if rctx.Routes == nil {
+		return ""
+	}
+
+	return rctx.Routes.Find(chi.NewRouteContext(), r.Method, cmp.Or(r.URL.RawPath, r.URL.Path))
+}
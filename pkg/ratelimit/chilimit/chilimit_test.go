@@ -0,0 +1,76 @@
+package chilimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/internal/ratelimittest"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestNew_RoutePolicyInSubrouter(t *testing.T) {
+	rl := ratelimittest.NewRouteLimiter(t)
+
+	r := chi.NewRouter()
+	r.Use(New(rl, ratelimit.WithRoutePolicy("/api/orders/{id}", "orders")))
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	// a política vale para o template, qualquer que seja o id
+	if code := ratelimittest.Do(t, r, "/api/orders/1").Code; code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if code := ratelimittest.Do(t, r, "/api/orders/2").Code; code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 from the route policy, got %d", code)
+	}
+
+	// as demais rotas seguem o limite por IP
+	for i := 0; i < 3; i++ {
+		if code := ratelimittest.Do(t, r, "/ping").Code; code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, code)
+		}
+	}
+	if code := ratelimittest.Do(t, r, "/ping").Code; code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 from the IP limit, got %d", code)
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	var got string
+	capture := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = RoutePattern(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Use(capture)
+	r.Get("/users/{id}", noop)
+	r.Route("/api", func(r chi.Router) {
+		r.Use(capture)
+		r.Get("/orders/{id}", noop)
+		r.With(capture).Get("/items/{id}", noop)
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/1", "/users/{id}"},
+		{"/api/orders/1", "/api/orders/{id}"},
+		{"/api/items/1", "/api/items/{id}"},
+		{"/missing", ""},
+	}
+	for _, tt := range tests {
+		got = "unset"
+		ratelimittest.Do(t, r, tt.path)
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package echolimit adapta o rate limiter ao echo. As políticas por rota usam o
+// template do echo, ex.: "/users/:id" ou "/files/*".
+package echolimit
+
+import (
+	"net/http"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+// New cria o middleware do echo para e.Use ou grupos; em e.Pre a rota ainda não
+// foi resolvida e as políticas por rota não se aplicam. O IP vem de c.RealIP,
+// que segue o IPExtractor do echo.
+func New(l *ratelimit.Limiter, opts ...ratelimit.RouteOption) echo.MiddlewareFunc {
+	rl := ratelimit.NewRouteLimiter(l, opts...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx, requestID, decision, err := rl.Decide(req.Context(), ratelimit.Request{
+				ID:     req.Header.Get("X-Request-ID"),
+				Method: req.Method,
+				Path:   req.URL.Path,
+				Route:  c.Path(),
+				IP:     c.RealIP(),
+				Token:  req.Header.Get("API_KEY"),
+			})
+			c.Response().Header().Set("X-Request-ID", requestID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError)
+			}
+
+			ratelimit.SetLimitHeaders(c.Response().Header(), decision)
+
+			if !decision.Allowed() {
+				return c.JSON(http.StatusTooManyRequests, ratelimit.ResponseMessage{Message: ratelimit.LimitExceededMessage})
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package fiberlimit adapta o rate limiter ao fiber. As políticas por rota usam
+// o template do fiber, ex.: "/users/:id" ou "/files/*".
+package fiberlimit
+
+import (
+	"net/http"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// New cria o handler do fiber. Em app.Use, c.Route() é a rota do próprio Use
+// (ex.: "/"), então as políticas por rota só se aplicam com o handler
+// registrado na rota: app.Get("/users/:id", fiberlimit.New(rl, ...), handler).
+// O IP vem de c.IP, que segue o ProxyHeader do app. As strings do fiber
+// apontam para o buffer do fasthttp, reusado depois do handler, então são
+// copiadas antes de ir para o limiter (request info, eventos e audit).
+func New(l *ratelimit.Limiter, opts ...ratelimit.RouteOption) fiber.Handler {
+	rl := ratelimit.NewRouteLimiter(l, opts...)
+
+	return func(c *fiber.Ctx) error {
+		ctx, requestID, decision, err := rl.Decide(c.UserContext(), ratelimit.Request{
+			ID:     utils.CopyString(c.Get("X-Request-ID")),
+			Method: utils.CopyString(c.Method()),
+			Path:   utils.CopyString(c.Path()),
+			Route:  c.Route().Path,
+			IP:     utils.CopyString(c.IP()),
+			Token:  utils.CopyString(c.Get("API_KEY")),
+		})
+		c.Set("X-Request-ID", requestID)
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
+
+		// o fiber não usa http.Header; os headers são montados pelo helper comum
+		headers := http.Header{}
+		ratelimit.SetLimitHeaders(headers, decision)
+		for name := range headers {
+			c.Set(name, headers.Get(name))
+		}
+
+		if !decision.Allowed() {
+			return c.Status(fiber.StatusTooManyRequests).JSON(ratelimit.ResponseMessage{Message: ratelimit.LimitExceededMessage})
+		}
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
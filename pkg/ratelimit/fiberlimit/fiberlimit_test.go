@@ -0,0 +1,50 @@
+package fiberlimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/internal/ratelimittest"
+	"github.com/gofiber/fiber/v2"
+)
+
+func do(t *testing.T, app *fiber.App, path string) *http.Response {
+	t.Helper()
+
+	resp, err := app.Test(ratelimittest.NewRequest(path))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestNew(t *testing.T) {
+	rl := ratelimittest.NewRouteLimiter(t)
+	ok := func(c *fiber.Ctx) error { return nil }
+
+	app := fiber.New()
+	app.Get("/ping", New(rl), ok)
+	app.Group("/api").Get("/orders/:id", New(rl, ratelimit.WithRoutePolicy("/api/orders/:id", "orders")), ok)
+
+	if resp := do(t, app, "/api/orders/1"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	resp := do(t, app, "/api/orders/2")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 from the route policy, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-RateLimit-Limit") != "1" || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected limit headers: %v", resp.Header)
+	}
+
+	for i := 0; i < 3; i++ {
+		if resp := do(t, app, "/ping"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, resp.StatusCode)
+		}
+	}
+	if resp := do(t, app, "/ping"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 from the IP limit, got %d", resp.StatusCode)
+	}
+}
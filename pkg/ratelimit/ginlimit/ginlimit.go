@@ -0,0 +1,42 @@
+// Package ginlimit adapta o rate limiter ao gin. As políticas por rota usam o
+// template do gin, ex.: "/users/:id" ou "/files/*path".
+package ginlimit
+
+import (
+	"net/http"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// New cria o middleware do gin. O IP vem de c.ClientIP, então os proxies
+// confiáveis são os configurados no engine (SetTrustedProxies).
+func New(l *ratelimit.Limiter, opts ...ratelimit.RouteOption) gin.HandlerFunc {
+	rl := ratelimit.NewRouteLimiter(l, opts...)
+
+	return func(c *gin.Context) {
+		ctx, requestID, decision, err := rl.Decide(c.Request.Context(), ratelimit.Request{
+			ID:     c.GetHeader("X-Request-ID"),
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+			Route:  c.FullPath(),
+			IP:     c.ClientIP(),
+			Token:  c.GetHeader("API_KEY"),
+		})
+		c.Header("X-Request-ID", requestID)
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		ratelimit.SetLimitHeaders(c.Writer.Header(), decision)
+
+		if !decision.Allowed() {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ratelimit.ResponseMessage{Message: ratelimit.LimitExceededMessage})
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
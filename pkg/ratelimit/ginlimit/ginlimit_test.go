@@ -0,0 +1,40 @@
+package ginlimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/internal/ratelimittest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNew(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rl := ratelimittest.NewRouteLimiter(t)
+
+	r := gin.New()
+	r.Use(New(rl, ratelimit.WithRoutePolicy("/api/orders/:id", "orders")))
+	r.GET("/ping", func(c *gin.Context) {})
+	r.Group("/api").GET("/orders/:id", func(c *gin.Context) {})
+
+	if rec := ratelimittest.Do(t, r, "/api/orders/1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	rec := ratelimittest.Do(t, r, "/api/orders/2")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 from the route policy, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "1" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected limit headers: %v", rec.Header())
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := ratelimittest.Do(t, r, "/ping"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := ratelimittest.Do(t, r, "/ping"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 from the IP limit, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,56 @@
+// Package ratelimittest contém os fixtures compartilhados pelos testes dos
+// adapters e do outbound.
+package ratelimittest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+)
+
+// ClientAddr é o endereço das requisições criadas por NewRequest
+const ClientAddr = "10.0.0.1:1234"
+
+// OrdersPolicy é a política usada nos testes de rota: uma requisição por minuto
+var OrdersPolicy = ratelimit.Policy{Name: "orders", Limit: 1, Window: time.Minute, BlockDuration: time.Minute}
+
+// NewLimiter cria um limiter sobre um storage em memória, fechado no fim do teste
+func NewLimiter(t *testing.T, opts ...ratelimit.Option) *ratelimit.Limiter {
+	t.Helper()
+
+	store := ratelimit.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	l, err := ratelimit.New(store, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return l
+}
+
+// NewRouteLimiter cria o limiter dos testes dos adapters: 3 req/s por IP e a
+// política OrdersPolicy
+func NewRouteLimiter(t *testing.T) *ratelimit.Limiter {
+	t.Helper()
+
+	return NewLimiter(t, ratelimit.WithIPLimit(3, time.Minute), ratelimit.WithPolicy(OrdersPolicy))
+}
+
+// NewRequest cria um GET para path vindo de ClientAddr
+func NewRequest(path string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ClientAddr
+	return req
+}
+
+// Do envia um GET para path ao handler e retorna a resposta gravada
+func Do(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, NewRequest(path))
+	return rec
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/alexduzi/labratelimiter/internal/dto"
+	"github.com/alexduzi/labratelimiter/internal/middleware"
+	"google.golang.org/grpc"
+)
+
+type (
+	// RouteOption configura o middleware HTTP e os adapters de frameworks
+	RouteOption = middleware.RouteOption
+	// RouteLimiter é a decisão por requisição sobre a qual os adapters de
+	// frameworks são construídos
+	RouteLimiter = middleware.RouteLimiter
+	// Request são os dados que o adapter extrai da requisição do framework
+	Request = middleware.Request
+	// ResponseMessage é o corpo JSON das respostas 429
+	ResponseMessage = dto.ResponseMessage
+	// GRPCOption configura os interceptors gRPC
+	GRPCOption  = middleware.GRPCOption
+	GRPCKeyFunc = middleware.GRPCKeyFunc
+	// TrustedProxies são as redes cujos headers X-Forwarded-For/X-Real-IP são
+	// confiáveis; sem elas o IP é sempre o da conexão
+	TrustedProxies = middleware.TrustedProxies
+)
+
+// LimitExceededMessage é a mensagem das respostas 429
+const LimitExceededMessage = middleware.LimitExceededMessage
+
+// Middleware limita as requisições de um http.Handler pelo token (header
+// API_KEY) ou pelo IP do cliente, respondendo 429 ao exceder
+func Middleware(l *Limiter, opts ...RouteOption) func(http.Handler) http.Handler {
+	return middleware.RateLimiter(l, opts...)
+}
+
+// NewRouteLimiter cria a base de um adapter: Decide retorna a decisão da
+// requisição, e SetLimitHeaders e LimitExceededMessage montam a resposta
+func NewRouteLimiter(l *Limiter, opts ...RouteOption) *RouteLimiter {
+	return middleware.NewRouteLimiter(l, opts...)
+}
+
+// SetLimitHeaders escreve os headers X-RateLimit-* e Retry-After da decisão
+func SetLimitHeaders(h http.Header, d Decision) {
+	middleware.SetLimitHeaders(h, d)
+}
+
+// WithRoutePolicy aplica a política às requisições da rota, identificada pelo
+// template do framework (ex.: "GET /users/{id}" no http.ServeMux)
+func WithRoutePolicy(route, policy string) RouteOption {
+	return middleware.WithRoutePolicy(route, policy)
+}
+
+// WithTrustedProxies lê o IP do cliente de X-Forwarded-For/X-Real-IP quando a
+// conexão vem de um dos proxies; sem esta opção os headers são ignorados
+func WithTrustedProxies(trusted TrustedProxies) RouteOption {
+	return middleware.WithTrustedProxies(trusted)
+}
+
+// ParseTrustedProxies interpreta IPs e CIDRs, ex.: "10.0.0.0/8"
+func ParseTrustedProxies(specs []string) (TrustedProxies, error) {
+	return middleware.ParseTrustedProxies(specs)
+}
+
+// ClientIP retorna o IP da conexão, sem ler headers; atrás de proxies use
+// TrustedProxies.ClientIP
+func ClientIP(r *http.Request) string {
+	return middleware.ClientIP(r)
+}
+
+// UnaryServerInterceptor limita as chamadas unárias pela metadata api-key ou
+// pelo IP do cliente, retornando codes.ResourceExhausted ao exceder
+func UnaryServerInterceptor(l *Limiter, opts ...GRPCOption) grpc.UnaryServerInterceptor {
+	return middleware.UnaryServerInterceptor(l, opts...)
+}
+
+// StreamServerInterceptor faz o mesmo na abertura de cada stream
+func StreamServerInterceptor(l *Limiter, opts ...GRPCOption) grpc.StreamServerInterceptor {
+	return middleware.StreamServerInterceptor(l, opts...)
+}
+
+// WithGRPCMethodPolicy aplica a política a um método ("/pkg.Service/Method") ou
+// a um serviço inteiro ("/pkg.Service/")
+func WithGRPCMethodPolicy(method, policy string) GRPCOption {
+	return middleware.WithGRPCMethodPolicy(method, policy)
+}
+
+// WithGRPCKeyFunc escolhe a política e a chave de cada chamada
+func WithGRPCKeyFunc(fn GRPCKeyFunc) GRPCOption {
+	return middleware.WithGRPCKeyFunc(fn)
+}
+
+// WithGRPCTrustedProxies lê o IP do cliente dos metadata x-forwarded-for e
+// x-real-ip quando a conexão vem de um dos proxies
+func WithGRPCTrustedProxies(trusted TrustedProxies) GRPCOption {
+	return middleware.WithGRPCTrustedProxies(trusted)
+}
+
+// WithGRPCSkip deixa os métodos fora do rate limit (ex.: health checks)
+func WithGRPCSkip(methods ...string) GRPCOption {
+	return middleware.WithGRPCSkip(methods...)
+}
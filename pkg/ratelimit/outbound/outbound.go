@@ -0,0 +1,194 @@
+// Package outbound aplica o limiter às chamadas que a aplicação faz a APIs de
+// terceiros, compartilhando o estado (ex.: Redis) entre as instâncias.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+)
+
+// ErrRateLimited indica que a chamada não foi feita por falta de capacidade
+var ErrRateLimited = errors.New("outbound rate limit exceeded")
+
+// RateLimitedError informa quanto esperar antes de tentar de novo
+type RateLimitedError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%v for %s, retry in %s", ErrRateLimited, e.Key, e.RetryAfter)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// KeyFunc escolhe a chave limitada de uma requisição
+type KeyFunc func(r *http.Request) string
+
+// ByHost limita por host (com porta, se houver); é a chave padrão
+func ByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+// Transport é um http.RoundTripper que consome uma unidade da política por
+// requisição antes de repassá-la ao RoundTripper base
+type Transport struct {
+	base     http.RoundTripper
+	rl       *ratelimit.Limiter
+	policy   string
+	key      KeyFunc
+	failFast bool
+	clock    ratelimit.Clock
+}
+
+type Option func(*Transport)
+
+// WithBase define o RoundTripper que faz as requisições (padrão: http.DefaultTransport)
+func WithBase(rt http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.base = rt
+	}
+}
+
+// WithKeyFunc substitui a chave padrão (ByHost)
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(t *Transport) {
+		t.key = fn
+	}
+}
+
+// WithFailFast retorna *RateLimitedError imediatamente em vez de esperar capacidade
+func WithFailFast() Option {
+	return func(t *Transport) {
+		t.failFast = true
+	}
+}
+
+// WithClock substitui o relógio usado para calcular as esperas; em testes deve
+// ser o mesmo passado ao limiter (ratelimit.WithClock)
+func WithClock(c ratelimit.Clock) Option {
+	return func(t *Transport) {
+		t.clock = c
+	}
+}
+
+// NewTransport cria o RoundTripper que limita as requisições pela política
+// informada. Por padrão espera capacidade, respeitando o deadline do contexto.
+func NewTransport(rl *ratelimit.Limiter, policy string, opts ...Option) (*Transport, error) {
+	if _, ok := rl.Policy(policy); !ok {
+		return nil, fmt.Errorf("%w: %q", ratelimit.ErrUnknownPolicy, policy)
+	}
+
+	t := &Transport{
+		base:   http.DefaultTransport,
+		rl:     rl,
+		policy: policy,
+		key:    ByHost,
+		clock:  clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := t.key(req)
+
+	if err := t.acquire(ctx, key); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// o upstream sabe mais que a política local: se ele pedir para esperar, a
+	// chave fica bloqueada para todas as instâncias até lá
+	if delay := upstreamDelay(resp, t.clock.Now()); delay > 0 {
+		if err := t.rl.Block(ctx, t.policy, key, delay); err != nil {
+			slog.WarnContext(ctx, "failed to apply upstream rate limit", slog.String("key", key), slog.Any("error", err))
+		}
+	}
+
+	return resp, nil
+}
+
+// acquire consome uma unidade da chave, esperando capacidade se necessário.
+// Usa Reserve, então esperar não bloqueia a chave ao exceder o limite.
+func (t *Transport) acquire(ctx context.Context, key string) error {
+	for {
+		r, err := t.rl.Reserve(ctx, t.policy, key, 1)
+		if err != nil {
+			return err
+		}
+		if r.OK() {
+			return nil
+		}
+
+		limited := &RateLimitedError{Key: key, RetryAfter: r.Delay()}
+		if t.failFast {
+			return limited
+		}
+		if deadline, ok := ctx.Deadline(); ok && t.clock.Now().Add(r.Delay()).After(deadline) {
+			return limited
+		}
+
+		timer := time.NewTimer(r.Delay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// upstreamDelay lê Retry-After (em 429/503) e os headers RateLimit-Remaining/
+// RateLimit-Reset, com ou sem o prefixo X-. O reset pode ser em segundos ou um
+// timestamp Unix.
+func upstreamDelay(resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if seconds, err := strconv.Atoi(v); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+			if at, err := http.ParseTime(v); err == nil {
+				return at.Sub(now)
+			}
+		}
+	}
+
+	for _, prefix := range []string{"", "X-"} {
+		remaining := resp.Header.Get(prefix + "RateLimit-Remaining")
+		if remaining != "0" {
+			continue
+		}
+
+		reset, err := strconv.ParseInt(resp.Header.Get(prefix+"RateLimit-Reset"), 10, 64)
+		if err != nil {
+			continue
+		}
+		// valores grandes demais para um intervalo são timestamps
+		if reset > 1_000_000_000 {
+			return time.Unix(reset, 0).Sub(now)
+		}
+		return time.Duration(reset) * time.Second
+	}
+
+	return 0
+}
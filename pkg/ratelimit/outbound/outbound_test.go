@@ -0,0 +1,173 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/internal/ratelimittest"
+)
+
+func newClient(t *testing.T, rl *ratelimit.Limiter, opts ...Option) *http.Client {
+	t.Helper()
+
+	transport, err := NewTransport(rl, "partner", opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &http.Client{Transport: transport}
+}
+
+func get(ctx context.Context, client *http.Client, url string) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestTransport_FailFast(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	rl := ratelimittest.NewLimiter(t, ratelimit.WithPolicy(ratelimit.Policy{Name: "partner", Limit: 2, Window: time.Minute, BlockDuration: time.Minute}))
+	client := newClient(t, rl, WithFailFast())
+
+	for i := 0; i < 2; i++ {
+		if err := get(context.Background(), client, server.URL); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i+1, err)
+		}
+	}
+
+	err := get(context.Background(), client, server.URL)
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected RateLimitedError, got %v", err)
+	}
+	if limited.RetryAfter <= 0 || limited.RetryAfter > time.Minute {
+		t.Errorf("expected retry within the window, got %v", limited.RetryAfter)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 upstream calls, got %d", calls.Load())
+	}
+
+	// esperar a capacidade não bloqueia a chave
+	state, _ := rl.State(context.Background(), "partner", limited.Key)
+	if state.Blocked() {
+		t.Error("expected the key not to be blocked")
+	}
+}
+
+func TestTransport_ConcurrentCallsDoNotBlockKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rl := ratelimittest.NewLimiter(t, ratelimit.WithPolicy(ratelimit.Policy{Name: "partner", Limit: 2, Window: time.Minute, BlockDuration: time.Minute}))
+	client := newClient(t, rl, WithFailFast())
+
+	var wg sync.WaitGroup
+	var ok atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := get(context.Background(), client, server.URL); err == nil {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ok.Load() != 2 {
+		t.Errorf("expected 2 calls within the limit, got %d", ok.Load())
+	}
+	state, _ := rl.State(context.Background(), "partner", ByHost(httptest.NewRequest(http.MethodGet, server.URL, nil)))
+	if state.Blocked() {
+		t.Error("expected concurrent callers not to block the key")
+	}
+}
+
+func TestTransport_WaitsForCapacity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rl := ratelimittest.NewLimiter(t, ratelimit.WithPolicy(ratelimit.Policy{Name: "partner", Limit: 1, Window: 200 * time.Millisecond, BlockDuration: 200 * time.Millisecond}))
+	client := newClient(t, rl)
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := get(context.Background(), client, server.URL); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i+1, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the second call to wait for the next window, took %v", elapsed)
+	}
+
+	// sem tempo suficiente até o deadline, falha sem esperar
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := get(ctx, client, server.URL); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited before the deadline, got %v", err)
+	}
+}
+
+func TestTransport_RespectsUpstreamHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/retry-after":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/reset":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(2*time.Minute).Unix(), 10))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path    string
+		atLeast time.Duration
+	}{
+		{"/retry-after", 29 * time.Second},
+		{"/reset", 110 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rl := ratelimittest.NewLimiter(t, ratelimit.WithPolicy(ratelimit.Policy{Name: "partner", Limit: 100, Window: time.Second, BlockDuration: time.Second}))
+			client := newClient(t, rl, WithFailFast())
+
+			if err := get(context.Background(), client, server.URL+tt.path); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var limited *RateLimitedError
+			if err := get(context.Background(), client, server.URL+tt.path); !errors.As(err, &limited) {
+				t.Fatalf("expected RateLimitedError, got %v", err)
+			}
+			if limited.RetryAfter < tt.atLeast {
+				t.Errorf("expected to wait at least %v, got %v", tt.atLeast, limited.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestNewTransport_RejectsUnknownPolicy(t *testing.T) {
+	rl := ratelimittest.NewLimiter(t, ratelimit.WithPolicy(ratelimit.Policy{Name: "partner", Limit: 1, Window: time.Second, BlockDuration: time.Second}))
+
+	if _, err := NewTransport(rl, "unknown"); !errors.Is(err, ratelimit.ErrUnknownPolicy) {
+		t.Errorf("expected ErrUnknownPolicy, got %v", err)
+	}
+}
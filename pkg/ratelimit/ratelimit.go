@@ -0,0 +1,130 @@
+// Package ratelimit é a API pública do rate limiter, para uso como biblioteca
+// em outros serviços Go. O limiter é montado com opções, sem depender de
+// variáveis de ambiente; o binário cmd/server é construído sobre este pacote.
+//
+//	store := ratelimit.NewMemoryStorage()
+//	defer store.Close()
+//
+//	l, err := ratelimit.New(store,
+//		ratelimit.WithIPLimit(10, 5*time.Minute),
+//		ratelimit.WithPolicy(ratelimit.Policy{Name: "login", Limit: 5, Window: time.Minute, BlockDuration: 15 * time.Minute}),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	http.ListenAndServe(":8080", ratelimit.Middleware(l)(mux))
+package ratelimit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/clock"
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/storage"
+)
+
+type (
+	// Limiter decide, consome e bloqueia as chaves de cada política
+	Limiter = limiter.RateLimiter
+	Option  = limiter.Option
+	// Policy é um limite nomeado: no máximo Limit unidades de custo por Window,
+	// e bloqueio por BlockDuration ao exceder
+	Policy      = limiter.Policy
+	Decision    = limiter.Decision
+	Outcome     = limiter.Outcome
+	Reservation = limiter.Reservation
+	BlockedKey  = limiter.BlockedKey
+	// Observer é notificado de forma síncrona de cada decisão
+	Observer = limiter.Observer
+	// StateObserver recebe também os bloqueios e desbloqueios manuais
+	StateObserver = limiter.StateObserver
+	Clock         = clock.Clock
+	// Storage guarda os contadores e bloqueios; implementações próprias seguem
+	// o contrato do pacote ratelimit/storage
+	Storage = storage.Storage
+	State   = storage.State
+)
+
+const (
+	// PolicyIP e PolicyToken são as políticas usadas pelo middleware HTTP
+	PolicyIP    = limiter.KindIP
+	PolicyToken = limiter.KindToken
+
+	OutcomeAllowed  = limiter.OutcomeAllowed
+	OutcomeDenied   = limiter.OutcomeDenied
+	OutcomeBlocked  = limiter.OutcomeBlocked
+	OutcomeFailOpen = limiter.OutcomeFailOpen
+)
+
+var (
+	ErrUnknownPolicy       = limiter.ErrUnknownKind
+	ErrInvalidCost         = limiter.ErrInvalidCost
+	ErrExceedsLimit        = limiter.ErrExceedsLimit
+	ErrWaitExceedsDeadline = limiter.ErrWaitExceedsDeadline
+)
+
+// New cria o limiter e valida as políticas registradas. Sem opções, permite
+// 10 req/s por IP e 100 req/s por token, com bloqueio de 5 minutos.
+func New(store Storage, opts ...Option) (*Limiter, error) {
+	if store == nil {
+		return nil, errors.New("storage is required")
+	}
+
+	l := limiter.New(store, opts...)
+
+	var errs []error
+	for _, p := range l.Policies() {
+		errs = append(errs, p.Validate())
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// WithIPLimit define quantas requisições por segundo cada IP pode fazer e por
+// quanto tempo ele fica bloqueado ao exceder
+func WithIPLimit(limit int, blockDuration time.Duration) Option {
+	return limiter.WithIPLimit(limit, blockDuration)
+}
+
+// WithTokenLimit define o mesmo para cada token (header API_KEY)
+func WithTokenLimit(limit int, blockDuration time.Duration) Option {
+	return limiter.WithTokenLimit(limit, blockDuration)
+}
+
+// WithPolicy registra uma política nomeada, usada com DecidePolicy, Wait e
+// WithRoutePolicy; os nomes "ip" e "token" substituem as políticas padrão
+func WithPolicy(p Policy) Option {
+	return limiter.WithPolicy(p)
+}
+
+// WithKeyPrefix define o prefixo e o namespace das chaves no storage
+// (padrão: "ratelimiter" e nenhum)
+func WithKeyPrefix(prefix, namespace string) Option {
+	return limiter.WithKeyPrefix(prefix, namespace)
+}
+
+// WithFailOpen libera as requisições quando o storage falha, em vez de retornar erro
+func WithFailOpen(enabled bool) Option {
+	return limiter.WithFailOpen(enabled)
+}
+
+// WithObserver registra um observer; pode ser usado mais de uma vez
+func WithObserver(o Observer) Option {
+	return limiter.WithObserver(o)
+}
+
+// WithClock substitui o relógio do limiter; em testes o mesmo relógio deve ser
+// passado ao storage (WithMemoryClock)
+func WithClock(c Clock) Option {
+	return limiter.WithClock(c)
+}
+
+// ParsePolicies interpreta políticas no formato "nome=limite/janela[/bloqueio]",
+// ex.: "login=5/1m/15m"
+func ParsePolicies(specs []string) ([]Policy, error) {
+	return limiter.ParsePolicies(specs)
+}
@@ -0,0 +1,67 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+)
+
+func TestNew_ValidatesPolicies(t *testing.T) {
+	store := ratelimit.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	if _, err := ratelimit.New(nil); err == nil {
+		t.Error("expected an error without storage")
+	}
+	if _, err := ratelimit.New(store, ratelimit.WithIPLimit(0, time.Minute)); err == nil {
+		t.Error("expected an error for a non-positive IP limit")
+	}
+	if _, err := ratelimit.New(store, ratelimit.WithPolicy(ratelimit.Policy{Name: "Login", Limit: 1, Window: time.Minute, BlockDuration: time.Minute})); err == nil {
+		t.Error("expected an error for an invalid policy name")
+	}
+}
+
+func TestNew_Options(t *testing.T) {
+	store := ratelimit.NewMemoryStorage()
+	t.Cleanup(func() { store.Close() })
+
+	l, err := ratelimit.New(store,
+		ratelimit.WithIPLimit(2, time.Minute),
+		ratelimit.WithKeyPrefix("app", "test"),
+		ratelimit.WithPolicy(ratelimit.Policy{Name: "login", Limit: 1, Window: time.Minute, BlockDuration: time.Minute}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	if d, _ := l.DecidePolicy(ctx, "login", "alice", 1); d.Outcome != ratelimit.OutcomeAllowed {
+		t.Fatalf("expected allowed, got %s", d.Outcome)
+	}
+	if d, _ := l.DecidePolicy(ctx, "login", "alice", 1); d.Outcome != ratelimit.OutcomeDenied {
+		t.Fatalf("expected denied, got %s", d.Outcome)
+	}
+
+	// as chaves usam o prefixo e o namespace informados
+	blocked, err := store.ListBlocked(ctx, "app:test:")
+	if err != nil || len(blocked) != 1 {
+		t.Errorf("expected one blocked key with the prefix, got %v (err=%v)", blocked, err)
+	}
+
+	handler := ratelimit.Middleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	codes := make([]int, 3)
+	for i := range codes {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes[i] = rec.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("expected the IP limit of 2 req/s, got %v", codes)
+	}
+}
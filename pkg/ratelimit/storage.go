@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/storage"
+)
+
+type (
+	MemoryOption = storage.MemoryOption
+	BoltOption   = storage.BoltOption
+)
+
+// NewMemoryStorage guarda os contadores na memória do processo; serve para uma
+// única instância ou testes. Close para a limpeza em background.
+func NewMemoryStorage(opts ...MemoryOption) Storage {
+	return storage.NewMemoryStorage(opts...)
+}
+
+// NewShardedMemoryStorage divide as chaves em shards com locks próprios, para
+// menos contenção sob concorrência alta (shards <= 0 usa o padrão, 32)
+func NewShardedMemoryStorage(shards int, opts ...MemoryOption) Storage {
+	return storage.NewShardedMemoryStorage(shards, opts...)
+}
+
+// WithMemoryMaxEntries limita o número de chaves mantidas em memória,
+// descartando a usada há mais tempo. Zero desativa o limite.
+func WithMemoryMaxEntries(n int) MemoryOption {
+	return storage.WithMemoryMaxEntries(n)
+}
+
+// WithMemoryCleanupInterval define de quanto em quanto tempo as entradas
+// expiradas são removidas. Zero desativa a limpeza em background.
+func WithMemoryCleanupInterval(d time.Duration) MemoryOption {
+	return storage.WithMemoryCleanupInterval(d)
+}
+
+// WithMemoryClock substitui o relógio usado para janelas e bloqueios
+func WithMemoryClock(c Clock) MemoryOption {
+	return storage.WithMemoryClock(c)
+}
+
+// NewRedisStorage conecta ao Redis, compartilhando os limites entre instâncias
+func NewRedisStorage(addr, password string, db int) (Storage, error) {
+	store, err := storage.NewRedisStorage(addr, password, db)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewBoltStorage persiste os contadores em um arquivo local (bbolt), para uma
+// única instância que precisa manter os bloqueios entre reinícios
+func NewBoltStorage(path string, opts ...BoltOption) (Storage, error) {
+	store, err := storage.NewBoltStorage(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// WithBoltCompactionInterval define de quanto em quanto tempo os registros
+// expirados são removidos do arquivo. Zero desativa a compactação.
+func WithBoltCompactionInterval(d time.Duration) BoltOption {
+	return storage.WithBoltCompactionInterval(d)
+}
@@ -0,0 +1,60 @@
+// Package storage define o contrato dos storages do rate limiter. Storages
+// próprios implementam Storage e são passados a ratelimit.New; a janela deve
+// ser fixa, começando no primeiro incremento (o TTL não é renovado a cada
+// incremento).
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrClosed é retornado por Ping depois que o storage foi fechado
+var ErrClosed = errors.New("storage closed")
+
+type Storage interface {
+	Increment(ctx context.Context, key string, window time.Duration) (int64, error)
+	// IncrementBy soma n (n >= 1) ao contador; uma janela nova começa em n
+	IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error)
+	// Take soma n ao contador só se a chave não estiver bloqueada e o total não
+	// passar de limit, como IncrementBy. O estado é lido na mesma operação
+	// atômica: com ok, é o de depois do incremento, e WindowStart é o da janela
+	// em que n foi contado (para Refund); sem ok, nada muda e o estado diz o
+	// motivo (BlockedUntil ou a janela cheia).
+	Take(ctx context.Context, key string, n, limit int64, window time.Duration) (State, bool, error)
+	// Refund subtrai n (n >= 1) do contador se a janela atual ainda é a que
+	// começou em windowStart. O contador nunca fica negativo e nenhuma janela é
+	// criada: sem janela ativa, ou com outra janela, nada muda.
+	Refund(ctx context.Context, key string, n int64, windowStart time.Time) error
+	IsBlocked(ctx context.Context, key string) (bool, error)
+	Block(ctx context.Context, key string, duration time.Duration) error
+	// Unblock remove apenas o bloqueio, preservando o contador da janela atual
+	Unblock(ctx context.Context, key string) error
+	// Reset remove o contador e o bloqueio da chave
+	Reset(ctx context.Context, key string) error
+	GetState(ctx context.Context, key string) (State, error)
+	// ListBlocked retorna as chaves com bloqueio ativo que começam com prefix
+	ListBlocked(ctx context.Context, prefix string) ([]BlockedKey, error)
+	// Ping verifica se o storage está acessível; usado pela readiness
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// State é o estado atual de uma chave. Janelas e bloqueios já expirados são
+// reportados como zero.
+type State struct {
+	Count        int64
+	WindowStart  time.Time
+	WindowEnd    time.Time
+	BlockedUntil time.Time
+}
+
+func (s State) Blocked() bool {
+	return !s.BlockedUntil.IsZero()
+}
+
+type BlockedKey struct {
+	Key          string
+	BlockedUntil time.Time
+}
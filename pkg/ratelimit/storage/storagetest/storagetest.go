@@ -0,0 +1,558 @@
+// Package storagetest contém a suíte de conformidade que todo storage.Storage
+// deve passar, garantindo a mesma semântica entre os backends.
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/storage"
+)
+
+// Window é a duração curta usada nos testes de expiração
+const Window = 200 * time.Millisecond
+
+// Factory cria um storage vazio para cada subteste. O fechamento fica a cargo da suíte.
+type Factory func(t *testing.T) storage.Storage
+
+type conformanceTest struct {
+	name string
+	run  func(t *testing.T, ctx context.Context, s storage.Storage)
+}
+
+var conformanceTests = []conformanceTest{
+	{name: "IncrementStartsAtOne", run: testIncrementStartsAtOne},
+	{name: "IncrementCountsWithinWindow", run: testIncrementCountsWithinWindow},
+	{name: "KeysAreIndependent", run: testKeysAreIndependent},
+	{name: "IncrementByAddsCost", run: testIncrementByAddsCost},
+	{name: "RefundSubtractsFromCurrentWindow", run: testRefundSubtractsFromCurrentWindow},
+	{name: "RefundStopsAtZero", run: testRefundStopsAtZero},
+	{name: "RefundUnknownKey", run: testRefundUnknownKey},
+	{name: "RefundIgnoresOtherWindow", run: testRefundIgnoresOtherWindow},
+	{name: "TakeWithinLimit", run: testTakeWithinLimit},
+	{name: "TakeRespectsBlock", run: testTakeRespectsBlock},
+	{name: "TakeStartsNewWindow", run: testTakeStartsNewWindow},
+	{name: "ConcurrentTakesNeverExceedLimit", run: testConcurrentTakesNeverExceedLimit},
+	{name: "WindowIsFixedNotSliding", run: testWindowIsFixedNotSliding},
+	{name: "NotBlockedByDefault", run: testNotBlockedByDefault},
+	{name: "BlockAndIsBlocked", run: testBlockAndIsBlocked},
+	{name: "BlockDoesNotAffectOtherKeys", run: testBlockDoesNotAffectOtherKeys},
+	{name: "BlockExpires", run: testBlockExpires},
+	{name: "ResetClearsCounter", run: testResetClearsCounter},
+	{name: "ResetClearsBlock", run: testResetClearsBlock},
+	{name: "UnblockKeepsCounter", run: testUnblockKeepsCounter},
+	{name: "UnblockUnknownKey", run: testUnblockUnknownKey},
+	{name: "GetStateUnknownKey", run: testGetStateUnknownKey},
+	{name: "GetStateReportsWindowAndBlock", run: testGetStateReportsWindowAndBlock},
+	{name: "GetStateIgnoresExpiredWindow", run: testGetStateIgnoresExpiredWindow},
+	{name: "ListBlockedFiltersByPrefix", run: testListBlockedFiltersByPrefix},
+	{name: "ListBlockedIgnoresExpiredBlocks", run: testListBlockedIgnoresExpiredBlocks},
+	{name: "ConcurrentIncrementsAreExact", run: testConcurrentIncrementsAreExact},
+	{name: "Ping", run: testPing},
+}
+
+// Run executa toda a suíte contra o storage criado por newStorage
+func Run(t *testing.T, newStorage Factory) {
+	t.Helper()
+
+	for _, tt := range conformanceTests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("failed to close storage: %v", err)
+				}
+			})
+
+			tt.run(t, context.Background(), s)
+		})
+	}
+}
+
+func increment(t *testing.T, ctx context.Context, s storage.Storage, key string, window time.Duration) int64 {
+	t.Helper()
+
+	count, err := s.Increment(ctx, key, window)
+	if err != nil {
+		t.Fatalf("increment %q: unexpected error: %v", key, err)
+	}
+	return count
+}
+
+func isBlocked(t *testing.T, ctx context.Context, s storage.Storage, key string) bool {
+	t.Helper()
+
+	blocked, err := s.IsBlocked(ctx, key)
+	if err != nil {
+		t.Fatalf("is blocked %q: unexpected error: %v", key, err)
+	}
+	return blocked
+}
+
+func block(t *testing.T, ctx context.Context, s storage.Storage, key string, duration time.Duration) {
+	t.Helper()
+
+	if err := s.Block(ctx, key, duration); err != nil {
+		t.Fatalf("block %q: unexpected error: %v", key, err)
+	}
+}
+
+func testIncrementStartsAtOne(t *testing.T, ctx context.Context, s storage.Storage) {
+	if count := increment(t, ctx, s, "ip:1.1.1.1", time.Minute); count != 1 {
+		t.Errorf("expected first increment to return 1, got %d", count)
+	}
+}
+
+func testIncrementCountsWithinWindow(t *testing.T, ctx context.Context, s storage.Storage) {
+	for i := int64(1); i <= 5; i++ {
+		if count := increment(t, ctx, s, "ip:1.1.1.1", time.Minute); count != i {
+			t.Errorf("increment %d: expected %d, got %d", i, i, count)
+		}
+	}
+}
+
+func testIncrementByAddsCost(t *testing.T, ctx context.Context, s storage.Storage) {
+	steps := []struct {
+		n    int64
+		want int64
+	}{
+		{5, 5},
+		{1, 6},
+		{10, 16},
+	}
+
+	for _, step := range steps {
+		count, err := s.IncrementBy(ctx, "ip:1.1.1.1", step.n, Window)
+		if err != nil {
+			t.Fatalf("increment by %d: unexpected error: %v", step.n, err)
+		}
+		if count != step.want {
+			t.Errorf("increment by %d: expected %d, got %d", step.n, step.want, count)
+		}
+	}
+
+	// a nova janela começa no valor do incremento
+	time.Sleep(Window + Window/5)
+	if count, err := s.IncrementBy(ctx, "ip:1.1.1.1", 3, Window); err != nil || count != 3 {
+		t.Errorf("expected a new window to start at 3, got %d (%v)", count, err)
+	}
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state.Count != 3 || state.WindowEnd.IsZero() {
+		t.Errorf("expected state with count 3 and a window, got %+v", state)
+	}
+}
+
+func refund(t *testing.T, ctx context.Context, s storage.Storage, key string, n int64, windowStart time.Time) {
+	t.Helper()
+
+	if err := s.Refund(ctx, key, n, windowStart); err != nil {
+		t.Fatalf("refund %q: unexpected error: %v", key, err)
+	}
+}
+
+func testRefundSubtractsFromCurrentWindow(t *testing.T, ctx context.Context, s storage.Storage) {
+	if _, err := s.IncrementBy(ctx, "ip:1.1.1.1", 5, time.Minute); err != nil {
+		t.Fatalf("increment by 5: unexpected error: %v", err)
+	}
+	before := getState(t, ctx, s, "ip:1.1.1.1")
+
+	refund(t, ctx, s, "ip:1.1.1.1", 3, before.WindowStart)
+
+	after := getState(t, ctx, s, "ip:1.1.1.1")
+	if after.Count != 2 {
+		t.Errorf("expected count 2 after refunding 3 of 5, got %d", after.Count)
+	}
+	if !after.WindowStart.Equal(before.WindowStart) || !after.WindowEnd.Equal(before.WindowEnd) {
+		t.Errorf("expected the window to be kept, got %+v (was %+v)", after, before)
+	}
+}
+
+// O contador não fica negativo e o próximo incremento continua na mesma janela
+func testRefundStopsAtZero(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	before := getState(t, ctx, s, "ip:1.1.1.1")
+
+	refund(t, ctx, s, "ip:1.1.1.1", 10, before.WindowStart)
+
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state.Count != 0 {
+		t.Errorf("expected refund to stop at 0, got %d", state.Count)
+	}
+
+	if count := increment(t, ctx, s, "ip:1.1.1.1", time.Minute); count != 1 {
+		t.Errorf("expected increment after refund to return 1, got %d", count)
+	}
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); !state.WindowEnd.Equal(before.WindowEnd) {
+		t.Errorf("expected the window ending at %v to be kept, got %v", before.WindowEnd, state.WindowEnd)
+	}
+}
+
+func testRefundUnknownKey(t *testing.T, ctx context.Context, s storage.Storage) {
+	refund(t, ctx, s, "ip:1.1.1.1", 1, time.Now())
+
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state != (storage.State{}) {
+		t.Errorf("expected refund not to create a window, got %+v", state)
+	}
+	if count := increment(t, ctx, s, "ip:1.1.1.1", time.Minute); count != 1 {
+		t.Errorf("expected first increment after refund to return 1, got %d", count)
+	}
+}
+
+// O custo consumido em uma janela que já terminou não é devolvido na seguinte
+func testRefundIgnoresOtherWindow(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", Window)
+	previous := getState(t, ctx, s, "ip:1.1.1.1")
+
+	time.Sleep(Window + Window/5)
+	increment(t, ctx, s, "ip:1.1.1.1", Window)
+	increment(t, ctx, s, "ip:1.1.1.1", Window)
+
+	refund(t, ctx, s, "ip:1.1.1.1", 1, previous.WindowStart)
+
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state.Count != 2 {
+		t.Errorf("expected refund of a previous window to be ignored, got count %d", state.Count)
+	}
+}
+
+func take(t *testing.T, ctx context.Context, s storage.Storage, key string, n, limit int64, window time.Duration) (storage.State, bool) {
+	t.Helper()
+
+	state, ok, err := s.Take(ctx, key, n, limit, window)
+	if err != nil {
+		t.Fatalf("take %q: unexpected error: %v", key, err)
+	}
+	return state, ok
+}
+
+// Take só consome o que cabe no limite e retorna a janela em que contou
+func testTakeWithinLimit(t *testing.T, ctx context.Context, s storage.Storage) {
+	first, ok := take(t, ctx, s, "ip:1.1.1.1", 3, 5, time.Minute)
+	if !ok || first.Count != 3 || first.WindowStart.IsZero() {
+		t.Fatalf("expected 3 to be taken in a new window, got %+v (ok=%v)", first, ok)
+	}
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); !state.WindowStart.Equal(first.WindowStart) || !state.WindowEnd.Equal(first.WindowEnd) {
+		t.Errorf("expected take to report the stored window %+v, got %+v", state, first)
+	}
+
+	if state, ok := take(t, ctx, s, "ip:1.1.1.1", 2, 5, time.Minute); !ok || state.Count != 5 || !state.WindowStart.Equal(first.WindowStart) {
+		t.Errorf("expected 2 more to fit in the same window, got %+v (ok=%v)", state, ok)
+	}
+
+	state, ok := take(t, ctx, s, "ip:1.1.1.1", 1, 5, time.Minute)
+	if ok || state.Count != 5 || !state.WindowEnd.Equal(first.WindowEnd) {
+		t.Errorf("expected the full window to refuse, got %+v (ok=%v)", state, ok)
+	}
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state.Count != 5 {
+		t.Errorf("expected a refused take not to change the counter, got %d", state.Count)
+	}
+}
+
+func testTakeRespectsBlock(t *testing.T, ctx context.Context, s storage.Storage) {
+	block(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	state, ok := take(t, ctx, s, "ip:1.1.1.1", 1, 5, time.Minute)
+	if ok || !state.Blocked() {
+		t.Fatalf("expected a blocked key to refuse, got %+v (ok=%v)", state, ok)
+	}
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state.Count != 0 {
+		t.Errorf("expected a blocked take not to count, got %d", state.Count)
+	}
+}
+
+func testTakeStartsNewWindow(t *testing.T, ctx context.Context, s storage.Storage) {
+	previous, _ := take(t, ctx, s, "ip:1.1.1.1", 2, 2, Window)
+
+	time.Sleep(Window + Window/5)
+	state, ok := take(t, ctx, s, "ip:1.1.1.1", 2, 2, Window)
+	if !ok || state.Count != 2 || !state.WindowStart.After(previous.WindowStart) {
+		t.Fatalf("expected a new window after the previous one ended, got %+v (was %+v)", state, previous)
+	}
+
+	refund(t, ctx, s, "ip:1.1.1.1", 2, previous.WindowStart)
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state.Count != 2 {
+		t.Errorf("expected refund of the previous window to be ignored, got count %d", state.Count)
+	}
+}
+
+func testConcurrentTakesNeverExceedLimit(t *testing.T, ctx context.Context, s storage.Storage) {
+	const workers, perWorker, limit = 8, 25, 100
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		taken int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				state, ok, err := s.Take(ctx, "token:shared", 1, limit, time.Minute)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				if state.Count > limit {
+					t.Errorf("take reported count %d above the limit", state.Count)
+				}
+				if ok {
+					mu.Lock()
+					taken++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if taken != limit {
+		t.Errorf("expected exactly %d takes to succeed, got %d", limit, taken)
+	}
+	if state := getState(t, ctx, s, "token:shared"); state.Count != limit {
+		t.Errorf("expected count %d, got %d", limit, state.Count)
+	}
+}
+
+func testKeysAreIndependent(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if count := increment(t, ctx, s, "ip:2.2.2.2", time.Minute); count != 1 {
+		t.Errorf("expected independent key to start at 1, got %d", count)
+	}
+}
+
+// A janela começa no primeiro incremento e não é estendida pelos seguintes
+func testWindowIsFixedNotSliding(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", Window)
+
+	time.Sleep(Window * 3 / 5)
+	if count := increment(t, ctx, s, "ip:1.1.1.1", Window); count != 2 {
+		t.Fatalf("expected count 2 inside the window, got %d", count)
+	}
+
+	time.Sleep(Window * 3 / 5)
+	if count := increment(t, ctx, s, "ip:1.1.1.1", Window); count != 1 {
+		t.Errorf("expected a new window after %s from the first increment, got count %d", Window, count)
+	}
+}
+
+func testNotBlockedByDefault(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if isBlocked(t, ctx, s, "ip:1.1.1.1") {
+		t.Error("expected key with only a counter not to be blocked")
+	}
+	if isBlocked(t, ctx, s, "ip:unknown") {
+		t.Error("expected unknown key not to be blocked")
+	}
+}
+
+func testBlockAndIsBlocked(t *testing.T, ctx context.Context, s storage.Storage) {
+	block(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if !isBlocked(t, ctx, s, "ip:1.1.1.1") {
+		t.Error("expected key to be blocked")
+	}
+}
+
+func testBlockDoesNotAffectOtherKeys(t *testing.T, ctx context.Context, s storage.Storage) {
+	block(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if isBlocked(t, ctx, s, "ip:2.2.2.2") {
+		t.Error("expected other key not to be blocked")
+	}
+	if count := increment(t, ctx, s, "ip:2.2.2.2", time.Minute); count != 1 {
+		t.Errorf("expected other key counter to start at 1, got %d", count)
+	}
+}
+
+func testBlockExpires(t *testing.T, ctx context.Context, s storage.Storage) {
+	block(t, ctx, s, "ip:1.1.1.1", Window)
+
+	time.Sleep(Window + Window/2)
+
+	if isBlocked(t, ctx, s, "ip:1.1.1.1") {
+		t.Errorf("expected block to expire after %s", Window)
+	}
+}
+
+func testResetClearsCounter(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if err := s.Reset(ctx, "ip:1.1.1.1"); err != nil {
+		t.Fatalf("reset: unexpected error: %v", err)
+	}
+
+	if count := increment(t, ctx, s, "ip:1.1.1.1", time.Minute); count != 1 {
+		t.Errorf("expected counter to restart at 1 after reset, got %d", count)
+	}
+}
+
+func getState(t *testing.T, ctx context.Context, s storage.Storage, key string) storage.State {
+	t.Helper()
+
+	state, err := s.GetState(ctx, key)
+	if err != nil {
+		t.Fatalf("get state %q: unexpected error: %v", key, err)
+	}
+	return state
+}
+
+func testResetClearsBlock(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	block(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if err := s.Reset(ctx, "ip:1.1.1.1"); err != nil {
+		t.Fatalf("reset: unexpected error: %v", err)
+	}
+
+	if isBlocked(t, ctx, s, "ip:1.1.1.1") {
+		t.Error("expected reset to clear the block")
+	}
+}
+
+func testUnblockKeepsCounter(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	block(t, ctx, s, "ip:1.1.1.1", time.Minute)
+
+	if err := s.Unblock(ctx, "ip:1.1.1.1"); err != nil {
+		t.Fatalf("unblock: unexpected error: %v", err)
+	}
+
+	if isBlocked(t, ctx, s, "ip:1.1.1.1") {
+		t.Error("expected unblock to clear the block")
+	}
+	if count := increment(t, ctx, s, "ip:1.1.1.1", time.Minute); count != 3 {
+		t.Errorf("expected unblock to keep the counter, got %d", count)
+	}
+}
+
+func testUnblockUnknownKey(t *testing.T, ctx context.Context, s storage.Storage) {
+	if err := s.Unblock(ctx, "ip:unknown"); err != nil {
+		t.Errorf("expected no error when unblocking an unknown key, got %v", err)
+	}
+}
+
+func testPing(t *testing.T, ctx context.Context, s storage.Storage) {
+	if err := s.Ping(ctx); err != nil {
+		t.Errorf("expected ping to succeed on an open storage, got %v", err)
+	}
+}
+
+func testGetStateUnknownKey(t *testing.T, ctx context.Context, s storage.Storage) {
+	if state := getState(t, ctx, s, "ip:unknown"); state != (storage.State{}) {
+		t.Errorf("expected zero state for unknown key, got %+v", state)
+	}
+}
+
+func testGetStateReportsWindowAndBlock(t *testing.T, ctx context.Context, s storage.Storage) {
+	before := time.Now().Add(-time.Second)
+
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	increment(t, ctx, s, "ip:1.1.1.1", time.Minute)
+	block(t, ctx, s, "ip:1.1.1.1", time.Hour)
+
+	state := getState(t, ctx, s, "ip:1.1.1.1")
+
+	if state.Count != 2 {
+		t.Errorf("expected count 2, got %d", state.Count)
+	}
+	if state.WindowStart.Before(before) || state.WindowStart.After(time.Now()) {
+		t.Errorf("expected window start close to now, got %v", state.WindowStart)
+	}
+	if got := state.WindowEnd.Sub(state.WindowStart); got != time.Minute {
+		t.Errorf("expected window of %s, got %s", time.Minute, got)
+	}
+	if !state.Blocked() || state.BlockedUntil.Before(before.Add(time.Hour)) {
+		t.Errorf("expected blocked until about an hour from now, got %v", state.BlockedUntil)
+	}
+}
+
+func testGetStateIgnoresExpiredWindow(t *testing.T, ctx context.Context, s storage.Storage) {
+	increment(t, ctx, s, "ip:1.1.1.1", Window)
+	block(t, ctx, s, "ip:1.1.1.1", Window)
+
+	time.Sleep(Window + Window/2)
+
+	if state := getState(t, ctx, s, "ip:1.1.1.1"); state != (storage.State{}) {
+		t.Errorf("expected zero state after window and block expired, got %+v", state)
+	}
+}
+
+func listBlocked(t *testing.T, ctx context.Context, s storage.Storage, prefix string) map[string]time.Time {
+	t.Helper()
+
+	keys, err := s.ListBlocked(ctx, prefix)
+	if err != nil {
+		t.Fatalf("list blocked %q: unexpected error: %v", prefix, err)
+	}
+
+	blocked := make(map[string]time.Time, len(keys))
+	for _, k := range keys {
+		blocked[k.Key] = k.BlockedUntil
+	}
+	return blocked
+}
+
+func testListBlockedFiltersByPrefix(t *testing.T, ctx context.Context, s storage.Storage) {
+	before := time.Now()
+
+	block(t, ctx, s, "app-a:ip:1.1.1.1", time.Hour)
+	block(t, ctx, s, "app-a:token:abc", time.Hour)
+	block(t, ctx, s, "app-b:ip:1.1.1.1", time.Hour)
+	increment(t, ctx, s, "app-a:ip:2.2.2.2", time.Minute)
+
+	blocked := listBlocked(t, ctx, s, "app-a:")
+
+	if len(blocked) != 2 {
+		t.Fatalf("expected 2 blocked keys with prefix, got %v", blocked)
+	}
+	for _, key := range []string{"app-a:ip:1.1.1.1", "app-a:token:abc"} {
+		until, ok := blocked[key]
+		if !ok {
+			t.Errorf("expected %q to be listed as blocked", key)
+			continue
+		}
+		if until.Before(before.Add(time.Hour - time.Second)) {
+			t.Errorf("expected %q blocked until about an hour from now, got %v", key, until)
+		}
+	}
+}
+
+func testListBlockedIgnoresExpiredBlocks(t *testing.T, ctx context.Context, s storage.Storage) {
+	block(t, ctx, s, "app:ip:1.1.1.1", Window)
+	block(t, ctx, s, "app:ip:2.2.2.2", time.Hour)
+
+	time.Sleep(Window + Window/2)
+
+	blocked := listBlocked(t, ctx, s, "app:")
+	if _, ok := blocked["app:ip:1.1.1.1"]; ok || len(blocked) != 1 {
+		t.Errorf("expected only the active block to be listed, got %v", blocked)
+	}
+}
+
+func testConcurrentIncrementsAreExact(t *testing.T, ctx context.Context, s storage.Storage) {
+	const workers, perWorker = 8, 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := s.Increment(ctx, "token:shared", time.Minute); err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count := increment(t, ctx, s, "token:shared", time.Minute); count != workers*perWorker+1 {
+		t.Errorf("expected count %d after concurrent increments, got %d", workers*perWorker+1, count)
+	}
+}
@@ -0,0 +1,202 @@
+package ratelimit_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/storage"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/storage/storagetest"
+)
+
+// mapStorage é um storage próprio, escrito só com os pacotes públicos
+type mapStorage struct {
+	mu      sync.Mutex
+	entries map[string]*mapEntry
+}
+
+type mapEntry struct {
+	count        int64
+	start, end   time.Time
+	blockedUntil time.Time
+}
+
+var _ storage.Storage = (*mapStorage)(nil)
+
+func newMapStorage() *mapStorage {
+	return &mapStorage{entries: make(map[string]*mapEntry)}
+}
+
+// window retorna a entrada com a janela ativa, se houver
+func (s *mapStorage) window(key string, now time.Time) (*mapEntry, bool) {
+	e, ok := s.entries[key]
+	return e, ok && now.Before(e.end)
+}
+
+func (s *mapStorage) Increment(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return s.IncrementBy(ctx, key, 1, window)
+}
+
+func (s *mapStorage) IncrementBy(ctx context.Context, key string, n int64, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.increment(key, n, window, time.Now()).count, nil
+}
+
+func (s *mapStorage) Take(ctx context.Context, key string, n, limit int64, window time.Duration) (storage.State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if state := s.state(key, now); state.Blocked() || state.Count+n > limit {
+		return state, false, nil
+	}
+	s.increment(key, n, window, now)
+	return s.state(key, now), true, nil
+}
+
+func (s *mapStorage) increment(key string, n int64, window time.Duration, now time.Time) *mapEntry {
+	if e, ok := s.window(key, now); ok {
+		e.count += n
+		return e
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &mapEntry{}
+		s.entries[key] = e
+	}
+	e.count, e.start, e.end = n, now, now.Add(window)
+	return e
+}
+
+func (s *mapStorage) Refund(ctx context.Context, key string, n int64, windowStart time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.window(key, time.Now()); ok && e.start.Equal(windowStart) {
+		e.count -= min(n, e.count)
+	}
+	return nil
+}
+
+func (s *mapStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	state, err := s.GetState(ctx, key)
+	return state.Blocked(), err
+}
+
+func (s *mapStorage) Block(ctx context.Context, key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &mapEntry{}
+		s.entries[key] = e
+	}
+	e.blockedUntil = time.Now().Add(duration)
+	return nil
+}
+
+func (s *mapStorage) Unblock(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.blockedUntil = time.Time{}
+	}
+	return nil
+}
+
+func (s *mapStorage) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *mapStorage) GetState(ctx context.Context, key string) (storage.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state(key, time.Now()), nil
+}
+
+func (s *mapStorage) state(key string, now time.Time) storage.State {
+	var state storage.State
+	if e, ok := s.window(key, now); ok {
+		state.Count, state.WindowStart, state.WindowEnd = e.count, e.start, e.end
+	}
+	if e, ok := s.entries[key]; ok && now.Before(e.blockedUntil) {
+		state.BlockedUntil = e.blockedUntil
+	}
+	return state
+}
+
+func (s *mapStorage) ListBlocked(ctx context.Context, prefix string) ([]storage.BlockedKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var blocked []storage.BlockedKey
+	now := time.Now()
+	for key, e := range s.entries {
+		if strings.HasPrefix(key, prefix) && now.Before(e.blockedUntil) {
+			blocked = append(blocked, storage.BlockedKey{Key: key, BlockedUntil: e.blockedUntil})
+		}
+	}
+	return blocked, nil
+}
+
+func (s *mapStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *mapStorage) Close() error {
+	return nil
+}
+
+func TestMapStorage_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newMapStorage()
+	})
+}
+
+func TestNew_WithExternalStorage(t *testing.T) {
+	l, err := ratelimit.New(newMapStorage(),
+		ratelimit.WithPolicy(ratelimit.Policy{Name: "login", Limit: 2, Window: time.Minute, BlockDuration: time.Minute}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	// a reserva cancelada devolve o custo pelo Refund do storage
+	r, err := l.Reserve(ctx, "login", "alice", 2)
+	if err != nil || !r.OK() {
+		t.Fatalf("expected reservation, got %v (err=%v)", r, err)
+	}
+	if err := r.Cancel(ctx); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if d, err := l.DecidePolicy(ctx, "login", "alice", 1); err != nil || d.Outcome != ratelimit.OutcomeAllowed {
+			t.Fatalf("call %d: expected allowed, got %s (err=%v)", i+1, d.Outcome, err)
+		}
+	}
+	if d, _ := l.DecidePolicy(ctx, "login", "alice", 1); d.Outcome != ratelimit.OutcomeDenied {
+		t.Fatalf("expected denied, got %s", d.Outcome)
+	}
+
+	blocked, err := l.ListBlocked(ctx)
+	if err != nil {
+		t.Fatalf("list blocked: %v", err)
+	}
+	if len(blocked) != 1 || blocked[0].Kind != "login" || blocked[0].ID != "alice" {
+		t.Errorf("expected login/alice to be blocked, got %+v", blocked)
+	}
+}
@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/offenders"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisOffenders_AggregatesInstances(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, addr := setupRedis(ctx, t)
+	t.Cleanup(func() {
+		if err := redisContainer.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate redis container: %v", err)
+		}
+	})
+
+	// a sincronização periódica fica longe; os dados vão para o Redis no Close e no Sync
+	a := offenders.NewRedis(redis.NewClient(&redis.Options{Addr: addr}), "ratelimiter:", time.Hour)
+	b := offenders.NewRedis(redis.NewClient(&redis.Options{Addr: addr}), "ratelimiter:", time.Hour)
+	t.Cleanup(func() { b.Close(ctx) })
+
+	for i := 0; i < 3; i++ {
+		a.ObserveDecision(ctx, limiter.Decision{Kind: limiter.KindIP, ID: "10.0.0.1", Policy: limiter.KindIP, Outcome: limiter.OutcomeDenied, Cost: 1})
+		b.ObserveDecision(ctx, limiter.Decision{Kind: limiter.KindIP, ID: "10.0.0.1", Policy: limiter.KindIP, Outcome: limiter.OutcomeDenied, Cost: 1})
+	}
+	b.ObserveDecision(ctx, limiter.Decision{Kind: limiter.KindToken, ID: "abc", Policy: limiter.KindToken, Outcome: limiter.OutcomeDenied, Cost: 1})
+
+	if err := a.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// fechar de novo não pode entrar em pânico nem falhar
+	if err := a.Close(ctx); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if err := b.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	got, err := b.Top(ctx, offenders.MetricDenials, 10)
+	if err != nil {
+		t.Fatalf("top: %v", err)
+	}
+	want := []offenders.Entry{
+		{Kind: limiter.KindIP, ID: "10.0.0.1", Count: 6},
+		{Kind: limiter.KindToken, ID: "abc", Count: 1},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
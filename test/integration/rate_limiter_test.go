@@ -11,7 +11,7 @@ import (
 
 	"github.com/alexduzi/labratelimiter/internal/config"
 	"github.com/alexduzi/labratelimiter/internal/dto"
-	"github.com/alexduzi/labratelimiter/internal/limiter"
+	"github.com/alexduzi/labratelimiter/internal/limiter/limiterconfig"
 	"github.com/alexduzi/labratelimiter/internal/middleware"
 	"github.com/alexduzi/labratelimiter/internal/storage"
 
@@ -101,7 +101,7 @@ func setupServer(t *testing.T) (*httptest.Server, *http.Client) {
 		t.Fatalf("failed to setup redis: %v", err)
 	}
 
-	rl := limiter.NewRateLimiter(store, cfg)
+	rl := limiterconfig.New(store, cfg)
 	mux := setupRouter(t)
 	handler := middleware.RateLimiter(rl)(mux)
 
@@ -0,0 +1,37 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexduzi/labratelimiter/internal/storage"
+	"github.com/alexduzi/labratelimiter/pkg/ratelimit/storage/storagetest"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStorage_Conformance(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, addr := setupRedis(ctx, t)
+	t.Cleanup(func() {
+		if err := redisContainer.Terminate(ctx); err != nil {
+			t.Errorf("failed to terminate redis container: %v", err)
+		}
+	})
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		// cada subteste começa com a database vazia
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		defer client.Close()
+
+		if err := client.FlushDB(ctx).Err(); err != nil {
+			t.Fatalf("failed to flush redis: %v", err)
+		}
+
+		store, err := storage.NewRedisStorage(addr, "", 0)
+		if err != nil {
+			t.Fatalf("failed to setup redis: %v", err)
+		}
+		return store
+	})
+}